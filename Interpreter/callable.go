@@ -0,0 +1,161 @@
+package interpreter
+
+import (
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/ast"
+	"github.com/shubhdevelop/YAPL/environment"
+)
+
+// YaplCallable is implemented by every runtime value that can appear as the
+// callee of a call expression.
+type YaplCallable interface {
+	Arity() int
+	Call(interpreter *Interpreter, arguments []interface{}) interface{}
+}
+
+// Return is panicked by a return statement and recovered by the enclosing
+// function call, unwinding every block and loop in between.
+type Return struct {
+	Value interface{}
+}
+
+// maxCallDepth matches the VM's frame limit, so both backends report a
+// stack overflow at the same depth instead of the Go runtime crashing.
+const maxCallDepth = 1024
+
+// activation is a call to a user function that hasn't returned yet.
+// callSite is the closing paren of the call in the caller, and caller the
+// environment the caller was running in.
+type activation struct {
+	function string
+	callSite token.Token
+	caller   *environment.Environment
+}
+
+// trace lists the active calls as yaplErrors frames, outermost first, with
+// at as the position in the innermost one.
+func (i *Interpreter) trace(at token.Token) []yaplErrors.Frame {
+	frames := make([]yaplErrors.Frame, 0, len(i.callStack)+1)
+	function := "<script>"
+	for _, activation := range i.callStack {
+		frames = append(frames, i.frame(function, activation.callSite))
+		function = activation.function
+	}
+	return append(frames, i.frame(function, at))
+}
+
+// CallDepth is the number of user function calls in progress.
+func (i *Interpreter) CallDepth() int {
+	return len(i.callStack)
+}
+
+// StackFrame is one active call as a debugger shows it: the function, where
+// it is executing and the environment it is executing in.
+type StackFrame struct {
+	Function    string
+	Line        int
+	Column      int
+	Environment *environment.Environment
+}
+
+// Frames lists the active calls, outermost first like trace, with at as the
+// position in the innermost one. It is meant for a DebugHook, when the
+// current environment is the innermost call's.
+func (i *Interpreter) Frames(at token.Token) []StackFrame {
+	frames := make([]StackFrame, 0, len(i.callStack)+1)
+	function := "<script>"
+	for _, activation := range i.callStack {
+		frames = append(frames, StackFrame{
+			Function:    function,
+			Line:        activation.callSite.Line,
+			Column:      activation.callSite.Column,
+			Environment: activation.caller,
+		})
+		function = activation.function
+	}
+	return append(frames, StackFrame{Function: function, Line: at.Line, Column: at.Column, Environment: i.Environment})
+}
+
+func (i *Interpreter) frame(function string, at token.Token) yaplErrors.Frame {
+	return yaplErrors.Frame{
+		Function: function,
+		File:     i.File,
+		Line:     at.Line,
+		Column:   at.Column,
+	}
+}
+
+// YaplFunction is the runtime representation of a user-defined function.
+// Closure is the environment that was active where the function was
+// declared, so the body sees those bindings rather than the caller's.
+type YaplFunction struct {
+	Declaration   ast.FunctionStmt
+	Closure       *environment.Environment
+	IsInitializer bool
+}
+
+var _ YaplCallable = (*YaplFunction)(nil)
+
+func (f *YaplFunction) Arity() int {
+	return len(f.Declaration.Params)
+}
+
+func (f *YaplFunction) Call(interpreter *Interpreter, arguments []interface{}) (result interface{}) {
+	depth := len(interpreter.callStack)
+	if depth+1 >= maxCallDepth {
+		panic(yaplErrors.RuntimeError{
+			Token:   interpreter.callSite,
+			Message: "Stack overflow.",
+		}.ThrowRuntimeError())
+	}
+	interpreter.callStack = append(interpreter.callStack, activation{
+		function: f.Declaration.Name.Lexeme,
+		callSite: interpreter.callSite,
+		caller:   interpreter.Environment,
+	})
+
+	environment := environment.NewEnclosedEnvironment(f.Closure)
+	for idx, param := range f.Declaration.Params {
+		environment.Define(param.Lexeme, arguments[idx])
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if returnValue, ok := r.(Return); ok {
+				interpreter.callStack = interpreter.callStack[:depth]
+				result = returnValue.Value
+				if f.IsInitializer {
+					result = f.Closure.GetAt(0, 0)
+				}
+				return
+			}
+			// Rethrow anything that isn't a return. The call stays on the
+			// stack so whoever recovers the error can trace it.
+			panic(r)
+		}
+	}()
+
+	interpreter.executeBlock(f.Declaration.Body, environment)
+	interpreter.callStack = interpreter.callStack[:depth]
+	if f.IsInitializer {
+		return f.Closure.GetAt(0, 0)
+	}
+	return nil
+}
+
+// Bind returns a copy of the method whose closure defines "this" as the
+// given instance.
+func (f *YaplFunction) Bind(instance *YaplInstance) *YaplFunction {
+	environment := environment.NewEnclosedEnvironment(f.Closure)
+	environment.Define("this", instance)
+	return &YaplFunction{
+		Declaration:   f.Declaration,
+		Closure:       environment,
+		IsInitializer: f.IsInitializer,
+	}
+}
+
+func (f *YaplFunction) String() string {
+	return "<fn " + f.Declaration.Name.Lexeme + ">"
+}
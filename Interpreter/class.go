@@ -0,0 +1,88 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+)
+
+// YaplClass is the runtime representation of a class declaration. Calling
+// a class creates a new instance and runs its init method, if any.
+type YaplClass struct {
+	Name       string
+	Superclass *YaplClass
+	Methods    map[string]*YaplFunction
+}
+
+var _ YaplCallable = (*YaplClass)(nil)
+
+func (c *YaplClass) FindMethod(name string) *YaplFunction {
+	if method, ok := c.Methods[name]; ok {
+		return method
+	}
+	if c.Superclass != nil {
+		return c.Superclass.FindMethod(name)
+	}
+	return nil
+}
+
+func (c *YaplClass) Arity() int {
+	if initializer := c.FindMethod("init"); initializer != nil {
+		return initializer.Arity()
+	}
+	return 0
+}
+
+func (c *YaplClass) Call(interpreter *Interpreter, arguments []interface{}) interface{} {
+	instance := NewYaplInstance(c)
+	if initializer := c.FindMethod("init"); initializer != nil {
+		initializer.Bind(instance).Call(interpreter, arguments)
+	}
+	return instance
+}
+
+func (c *YaplClass) String() string {
+	return c.Name
+}
+
+// YaplInstance is an object created by calling a YaplClass.
+type YaplInstance struct {
+	Class  *YaplClass
+	Fields map[string]interface{}
+	// At is where the runtime error an Error instance stands for happened,
+	// kept so that rethrowing it reports the same position.
+	At token.Token
+}
+
+func NewYaplInstance(class *YaplClass) *YaplInstance {
+	return &YaplInstance{
+		Class:  class,
+		Fields: make(map[string]interface{}),
+	}
+}
+
+// Get looks up a field first and falls back to a method bound to this
+// instance, so fields shadow methods of the same name.
+func (in *YaplInstance) Get(name token.Token) interface{} {
+	if value, ok := in.Fields[name.Lexeme]; ok {
+		return value
+	}
+	if method := in.Class.FindMethod(name.Lexeme); method != nil {
+		return method.Bind(in)
+	}
+
+	runtimeError := yaplErrors.RuntimeError{
+		Token:   name,
+		Message: fmt.Sprintf("Undefined property '%s'.", name.Lexeme),
+	}
+	panic(runtimeError.ThrowRuntimeError())
+}
+
+func (in *YaplInstance) Set(name token.Token, value interface{}) {
+	in.Fields[name.Lexeme] = value
+}
+
+func (in *YaplInstance) String() string {
+	return in.Class.Name + " instance"
+}
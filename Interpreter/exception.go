@@ -0,0 +1,62 @@
+package interpreter
+
+import (
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+)
+
+// Throw is panicked by a throw statement and recovered by the nearest
+// enclosing try statement with a catch clause. If nothing catches it,
+// Execute returns it as a yaplErrors.RuntimeError.
+type Throw struct {
+	Value   interface{}
+	Keyword token.Token
+}
+
+func (t Throw) Error() string {
+	return t.runtimeError().Error()
+}
+
+// runtimeError describes a throw that nobody caught. A caught runtime error
+// that is thrown again is reported as the original error.
+func (t Throw) runtimeError() yaplErrors.RuntimeError {
+	if message, at, ok := errorFields(t.Value); ok {
+		return yaplErrors.RuntimeError{
+			Token:   at,
+			Message: message,
+		}
+	}
+	return yaplErrors.RuntimeError{
+		Token:   t.Keyword,
+		Message: "Uncaught " + stringify(t.Value),
+	}
+}
+
+// errorClass is the class of the values a catch clause receives for runtime
+// errors raised by the interpreter itself. Each instance carries the error's
+// message and the line it happened on.
+var errorClass = &YaplClass{
+	Name:    "Error",
+	Methods: map[string]*YaplFunction{},
+}
+
+func newErrorValue(err yaplErrors.RuntimeError) *YaplInstance {
+	instance := NewYaplInstance(errorClass)
+	instance.Fields["message"] = err.Message
+	instance.Fields["line"] = float64(err.Token.Line)
+	instance.Fields["column"] = float64(err.Token.Column)
+	instance.At = err.Token
+	return instance
+}
+
+// errorFields reports the message and position of a value created by
+// newErrorValue, so rethrowing a caught runtime error reports it the same
+// way as if it had never been caught.
+func errorFields(value interface{}) (string, token.Token, bool) {
+	instance, ok := value.(*YaplInstance)
+	if !ok || instance.Class != errorClass {
+		return "", token.Token{}, false
+	}
+	message, _ := instance.Fields["message"].(string)
+	return message, instance.At, true
+}
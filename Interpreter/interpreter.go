@@ -2,17 +2,51 @@ package interpreter
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 
 	"github.com/shubhdevelop/YAPL/Token"
 	"github.com/shubhdevelop/YAPL/YaplErrors"
 	"github.com/shubhdevelop/YAPL/ast"
 	"github.com/shubhdevelop/YAPL/environment"
-	"github.com/shubhdevelop/YAPL/state"
 )
 
 type Interpreter struct {
+	Globals     *environment.Environment
 	Environment *environment.Environment
+	// Stdout receives the output of print statements and the runtime
+	// errors Interpret reports.
+	Stdout io.Writer
+	// File is the source name reported in runtime error traces.
+	File string
+	// Files, if set, holds the sources Interpret quotes runtime errors
+	// from.
+	Files *token.FileSet
+	// DebugHook, if set, is called before each statement runs, so a
+	// debugger can stop there and inspect the program with CallDepth,
+	// Frames and the Environment chain.
+	DebugHook func(stmt ast.Stmt)
+	// callStack holds the user function calls in progress. callSite is the
+	// closing paren of the call about to be made, recorded for the frame
+	// that call pushes.
+	callStack []activation
+	callSite  token.Token
+	// abruptCompletion and continueException are set by break and continue
+	// and unwind blocks until the enclosing while loop clears them.
+	abruptCompletion  bool
+	continueException bool
+}
+
+func NewInterpreter() *Interpreter {
+	globals := environment.NewEnvironment()
+	interpreter := &Interpreter{
+		Globals:     globals,
+		Environment: globals,
+		Stdout:      os.Stdout,
+	}
+	interpreter.defineBuiltins()
+	return interpreter
 }
 
 var _ ast.ExprVisitor = (*Interpreter)(nil)
@@ -27,7 +61,7 @@ func (i *Interpreter) VisitBinaryExpr(expr ast.Binary) interface{} {
 		l, lok := left.(float64)
 		r, rok := right.(float64)
 		if !lok || !rok {
-			panic("Operands must be numbers.") // or return an error type
+			i.checkNumberOperand(expr.Operator, left, right)
 		}
 
 		switch expr.Operator.Type {
@@ -49,6 +83,7 @@ func (i *Interpreter) VisitBinaryExpr(expr ast.Binary) interface{} {
 	case token.EQUAL_EQUAL:
 		return i.isEqual(left, right)
 	case token.MINUS:
+		i.checkNumberOperand(expr.Operator, left, right)
 		return left.(float64) - right.(float64)
 	case token.PLUS:
 		runtimeError := yaplErrors.RuntimeError{
@@ -68,8 +103,8 @@ func (i *Interpreter) VisitBinaryExpr(expr ast.Binary) interface{} {
 			} else {
 				panic(runtimeError.ThrowRuntimeError())
 			}
-
 		}
+		panic(runtimeError.ThrowRuntimeError())
 	case token.SLASH:
 		i.checkNumberOperand(expr.Operator, left, right)
 		return left.(float64) / right.(float64)
@@ -80,6 +115,138 @@ func (i *Interpreter) VisitBinaryExpr(expr ast.Binary) interface{} {
 	return nil
 }
 
+func (i *Interpreter) VisitCallExpr(expr ast.Call) interface{} {
+	callee := i.evaluate(expr.Callee)
+
+	arguments := []interface{}{}
+	for _, argument := range expr.Arguments {
+		arguments = append(arguments, i.evaluate(argument))
+	}
+
+	function, ok := callee.(YaplCallable)
+	if !ok {
+		runtimeError := yaplErrors.RuntimeError{
+			Token:   expr.Paren,
+			Message: "Can only call functions and classes.",
+		}
+		panic(runtimeError.ThrowRuntimeError())
+	}
+	if len(arguments) != function.Arity() {
+		runtimeError := yaplErrors.RuntimeError{
+			Token:   expr.Paren,
+			Message: fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), len(arguments)),
+		}
+		panic(runtimeError.ThrowRuntimeError())
+	}
+
+	i.callSite = expr.Paren
+	return function.Call(i, arguments)
+}
+
+func (i *Interpreter) VisitGetExpr(expr ast.Get) interface{} {
+	object := i.evaluate(expr.Object)
+	if instance, ok := object.(*YaplInstance); ok {
+		return instance.Get(expr.Name)
+	}
+
+	runtimeError := yaplErrors.RuntimeError{
+		Token:   expr.Name,
+		Message: "Only instances have properties.",
+	}
+	panic(runtimeError.ThrowRuntimeError())
+}
+
+func (i *Interpreter) VisitSetExpr(expr ast.Set) interface{} {
+	object := i.evaluate(expr.Object)
+	instance, ok := object.(*YaplInstance)
+	if !ok {
+		runtimeError := yaplErrors.RuntimeError{
+			Token:   expr.Name,
+			Message: "Only instances have fields.",
+		}
+		panic(runtimeError.ThrowRuntimeError())
+	}
+
+	value := i.evaluate(expr.Value)
+	instance.Set(expr.Name, value)
+	return value
+}
+
+func (i *Interpreter) VisitSuperExpr(expr ast.Super) interface{} {
+	superclass := i.Environment.GetAt(expr.Binding.Depth, expr.Binding.Slot).(*YaplClass)
+	// "this" is always bound in the scope just inside the one holding "super"
+	object := i.Environment.GetAt(expr.Binding.Depth-1, 0).(*YaplInstance)
+
+	method := superclass.FindMethod(expr.Method.Lexeme)
+	if method == nil {
+		runtimeError := yaplErrors.RuntimeError{
+			Token:   expr.Method,
+			Message: fmt.Sprintf("Undefined property '%s'.", expr.Method.Lexeme),
+		}
+		panic(runtimeError.ThrowRuntimeError())
+	}
+	return method.Bind(object)
+}
+
+func (i *Interpreter) VisitThisExpr(expr ast.This) interface{} {
+	return i.lookUpVariable(expr.Keyword, expr.Binding)
+}
+
+func (i *Interpreter) VisitListExpr(expr ast.List) interface{} {
+	elements := make([]interface{}, 0, len(expr.Elements))
+	for _, element := range expr.Elements {
+		elements = append(elements, i.evaluate(element))
+	}
+	return &YaplList{Elements: elements}
+}
+
+func (i *Interpreter) VisitMapExpr(expr ast.Map) interface{} {
+	m := NewYaplMap()
+	for idx, keyExpr := range expr.Keys {
+		key := i.evaluate(keyExpr)
+		value := i.evaluate(expr.Values[idx])
+		m.Set(expr.Brace, key, value)
+	}
+	return m
+}
+
+func (i *Interpreter) VisitIndexExpr(expr ast.Index) interface{} {
+	object := i.evaluate(expr.Object)
+	index := i.evaluate(expr.Index)
+	switch collection := object.(type) {
+	case *YaplList:
+		return collection.Get(expr.Bracket, index)
+	case *YaplMap:
+		return collection.Get(expr.Bracket, index)
+	}
+
+	runtimeError := yaplErrors.RuntimeError{
+		Token:   expr.Bracket,
+		Message: "Only lists and maps can be indexed.",
+	}
+	panic(runtimeError.ThrowRuntimeError())
+}
+
+func (i *Interpreter) VisitIndexSetExpr(expr ast.IndexSet) interface{} {
+	object := i.evaluate(expr.Object)
+	index := i.evaluate(expr.Index)
+	value := i.evaluate(expr.Value)
+	switch collection := object.(type) {
+	case *YaplList:
+		collection.Set(expr.Bracket, index, value)
+		return value
+	case *YaplMap:
+		collection.Set(expr.Bracket, index, value)
+		return value
+	}
+
+	runtimeError := yaplErrors.RuntimeError{
+		Token:   expr.Bracket,
+		Message: "Only lists and maps can be indexed.",
+	}
+	panic(runtimeError.ThrowRuntimeError())
+}
+
 func (i *Interpreter) VisitGroupingExpr(expr ast.Grouping) interface{} {
 	return i.evaluate(expr.Expression)
 }
@@ -94,7 +261,14 @@ func (i *Interpreter) VisitUnaryExpr(expr ast.Unary) interface{} {
 	case token.BANG:
 		return !i.isTruthy(right)
 	case token.MINUS:
-		return -right.(float64)
+		value, ok := right.(float64)
+		if !ok {
+			panic(yaplErrors.RuntimeError{
+				Token:   expr.Operator,
+				Message: "Operand must be a number.",
+			}.ThrowRuntimeError())
+		}
+		return -value
 	}
 	return nil
 }
@@ -114,6 +288,18 @@ func (i *Interpreter) isTruthy(object interface{}) bool {
 }
 
 func (i *Interpreter) isEqual(a, b interface{}) bool {
+	return valuesEqual(a, b, nil)
+}
+
+// comparison is a pair of lists or maps being compared.
+type comparison struct {
+	a, b interface{}
+}
+
+// valuesEqual compares a and b inside the comparisons in progress. Lists and
+// maps can contain themselves, so a pair reached again while it is being
+// compared is taken to be equal; any difference shows up elsewhere.
+func valuesEqual(a, b interface{}, comparing []comparison) bool {
 	// Handle nil explicitly
 	if a == nil && b == nil {
 		return true
@@ -136,28 +322,119 @@ func (i *Interpreter) isEqual(a, b interface{}) bool {
 		if y, ok := b.(string); ok {
 			return x == y
 		}
+	case *YaplFunction, *NativeFunction, *YaplClass, *YaplInstance:
+		// Functions, classes and instances are only equal to themselves
+		return a == b
+	case *YaplList:
+		// Lists are equal when they hold equal elements in the same order
+		if y, ok := b.(*YaplList); ok {
+			if x == y || isComparing(comparing, x, y) {
+				return true
+			}
+			if len(x.Elements) != len(y.Elements) {
+				return false
+			}
+			comparing = append(comparing, comparison{x, y})
+			for idx := range x.Elements {
+				if !valuesEqual(x.Elements[idx], y.Elements[idx], comparing) {
+					return false
+				}
+			}
+			return true
+		}
+	case *YaplMap:
+		// Maps are equal when they hold equal values under the same keys
+		if y, ok := b.(*YaplMap); ok {
+			if x == y || isComparing(comparing, x, y) {
+				return true
+			}
+			if x.Len() != y.Len() {
+				return false
+			}
+			comparing = append(comparing, comparison{x, y})
+			for _, key := range x.keys {
+				value, ok := y.Lookup(key)
+				if !ok || !valuesEqual(x.entries[key], value, comparing) {
+					return false
+				}
+			}
+			return true
+		}
 	}
 
 	return false // types don't match or not comparable
 }
 
-func (i *Interpreter) Interpret(stmts []ast.Stmt) {
+func isComparing(comparing []comparison, a, b interface{}) bool {
+	for _, c := range comparing {
+		if c.a == a && c.b == b {
+			return true
+		}
+	}
+	return false
+}
+
+// Interpret runs stmts, printing any runtime error to Stdout, and returns
+// that error.
+func (i *Interpreter) Interpret(stmts []ast.Stmt) error {
+	_, err := i.Execute(stmts)
+	if err != nil {
+		fmt.Fprintln(i.Stdout, "Runtime error:", yaplErrors.FormatError(err, i.Files))
+	}
+	return err
+}
+
+// Execute runs stmts and returns the value of the last statement when it is
+// an expression statement. A runtime error or an uncaught throw stops
+// execution and is returned as a yaplErrors.RuntimeError instead of printed.
+func (i *Interpreter) Execute(stmts []ast.Stmt) (result interface{}, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			// Equivalent to catching RuntimeError in Java
-			fmt.Println("Runtime error:", r)
+			i.abruptCompletion = false
+			i.continueException = false
+			switch e := r.(type) {
+			case yaplErrors.RuntimeError:
+				e.Trace = i.trace(e.Token)
+				err = e
+			case Throw:
+				runtimeError := e.runtimeError()
+				runtimeError.Trace = i.trace(e.Keyword)
+				err = runtimeError
+			case error:
+				err = e
+			default:
+				err = fmt.Errorf("%v", r)
+			}
+			i.callStack = i.callStack[:0]
 		}
 	}()
 
-	for _, stmt := range stmts {
+	for idx, stmt := range stmts {
+		if exprStmt, ok := stmt.(ast.ExpressionStmt); ok && idx == len(stmts)-1 {
+			if i.DebugHook != nil {
+				i.DebugHook(stmt)
+			}
+			return i.evaluate(exprStmt.Expression), nil
+		}
 		i.execute(stmt)
 	}
+	return nil, nil
 }
 
 func (i *Interpreter) execute(stmt ast.Stmt) {
+	if i.DebugHook != nil {
+		i.DebugHook(stmt)
+	}
 	stmt.Accept(i)
 }
 
+// Stringify formats value the way a print statement would, for hosts such
+// as the REPL that echo results.
+func Stringify(value interface{}) string {
+	return stringify(value)
+}
+
 // stringify matches Lox semantics
 func stringify(obj interface{}) string {
 	if obj == nil {
@@ -205,7 +482,7 @@ func (i *Interpreter) VisitExpressionStmtStmt(stmt ast.ExpressionStmt) interface
 
 func (i *Interpreter) VisitPrintStmtStmt(stmt ast.PrintStmt) interface{} {
 	value := i.evaluate(stmt.Expression)
-	fmt.Println(stringify(value))
+	fmt.Fprintln(i.Stdout, stringify(value))
 	return nil
 }
 
@@ -221,13 +498,26 @@ func (i *Interpreter) VisitVarStmtStmt(stmt ast.VarStmt) interface{} {
 }
 
 func (i *Interpreter) VisitVariableExpr(expr ast.Variable) interface{} {
-	value, _ := i.Environment.Get(expr.Name)
+	return i.lookUpVariable(expr.Name, expr.Binding)
+}
+
+// lookUpVariable reads a local at the distance the resolver computed, or
+// falls back to a by-name lookup in the globals.
+func (i *Interpreter) lookUpVariable(name token.Token, binding *ast.Binding) interface{} {
+	if !binding.IsGlobal() {
+		return i.Environment.GetAt(binding.Depth, binding.Slot)
+	}
+	value, _ := i.Globals.Get(name)
 	return value
 }
 
 func (i *Interpreter) VisitAssignExpr(expr ast.Assign) interface{} {
 	value := i.evaluate(expr.Value)
-	i.Environment.Assign(expr.Name, value)
+	if !expr.Binding.IsGlobal() {
+		i.Environment.AssignAt(expr.Binding.Depth, expr.Binding.Slot, value)
+	} else {
+		i.Globals.Assign(expr.Name, value)
+	}
 	return value
 }
 
@@ -264,14 +554,16 @@ func (i *Interpreter) VisitWhileStmtStmt(stmt ast.WhileStmt) interface{} {
 	for i.isTruthy(i.evaluate(stmt.Condition)) {
 		i.execute(stmt.Body)
 
-		if state.ContinueException {
-			state.ContinueException = false
-			continue
+		if i.continueException {
+			i.continueException = false
 		}
-		if state.AbruptCompletion {
-			state.AbruptCompletion = false
+		if i.abruptCompletion {
+			i.abruptCompletion = false
 			break
 		}
+		if stmt.Increment != nil {
+			i.evaluate(stmt.Increment)
+		}
 	}
 	return nil
 }
@@ -286,21 +578,139 @@ func (i *Interpreter) executeBlock(statements []ast.Stmt, environment *environme
 	i.Environment = environment
 	for _, stmt := range statements {
 		i.execute(stmt)
-		if state.ContinueException {
+		if i.continueException {
 			return // Exit the block immediately for continue, let while loop handle the flag
 		}
-		if state.AbruptCompletion {
+		if i.abruptCompletion {
 			return // Exit the block immediately for break, let while loop handle the flag
 		}
 	}
 }
 
+func (i *Interpreter) VisitFunctionStmtStmt(stmt ast.FunctionStmt) interface{} {
+	function := &YaplFunction{
+		Declaration: stmt,
+		Closure:     i.Environment,
+	}
+	i.Environment.Define(stmt.Name.Lexeme, function)
+	return nil
+}
+
+func (i *Interpreter) VisitClassStmtStmt(stmt ast.ClassStmt) interface{} {
+	var superclass *YaplClass
+	if stmt.Superclass != nil {
+		class, ok := i.evaluate(*stmt.Superclass).(*YaplClass)
+		if !ok {
+			runtimeError := yaplErrors.RuntimeError{
+				Token:   stmt.Superclass.Name,
+				Message: "Superclass must be a class.",
+			}
+			panic(runtimeError.ThrowRuntimeError())
+		}
+		superclass = class
+	}
+
+	i.Environment.Define(stmt.Name.Lexeme, nil)
+
+	if superclass != nil {
+		i.Environment = environment.NewEnclosedEnvironment(i.Environment)
+		i.Environment.Define("super", superclass)
+	}
+
+	methods := make(map[string]*YaplFunction)
+	for _, method := range stmt.Methods {
+		methods[method.Name.Lexeme] = &YaplFunction{
+			Declaration:   method,
+			Closure:       i.Environment,
+			IsInitializer: method.Name.Lexeme == "init",
+		}
+	}
+
+	class := &YaplClass{
+		Name:       stmt.Name.Lexeme,
+		Superclass: superclass,
+		Methods:    methods,
+	}
+
+	if superclass != nil {
+		i.Environment = i.Environment.Enclosing
+	}
+	i.Environment.Assign(stmt.Name, class)
+	return nil
+}
+
+func (i *Interpreter) VisitReturnStmtStmt(stmt ast.ReturnStmt) interface{} {
+	var value interface{} = nil
+	if stmt.Value != nil {
+		value = i.evaluate(stmt.Value)
+	}
+	panic(Return{Value: value})
+}
+
 func (i *Interpreter) VisitBreakStmtStmt(stmt ast.BreakStmt) interface{} {
-	state.AbruptCompletion = true
+	i.abruptCompletion = true
 	return nil
 }
 
 func (i *Interpreter) VisitContinueStmtStmt(stmt ast.ContinueStmt) interface{} {
-	state.ContinueException = true
+	i.continueException = true
+	return nil
+}
+
+func (i *Interpreter) VisitThrowStmtStmt(stmt ast.ThrowStmt) interface{} {
+	panic(Throw{Value: i.evaluate(stmt.Value), Keyword: stmt.Keyword})
+}
+
+func (i *Interpreter) VisitTryStmtStmt(stmt ast.TryStmt) interface{} {
+	if stmt.FinallyBody != nil {
+		// Deferred so the finally block also runs while a return, an
+		// uncaught throw or a runtime error unwinds through this statement.
+		defer i.executeFinally(stmt.FinallyBody)
+	}
+	if stmt.CatchName == nil {
+		i.executeBlock(stmt.Body, environment.NewEnclosedEnvironment(i.Environment))
+		return nil
+	}
+
+	if thrown, caught := i.executeTry(stmt.Body); caught {
+		environment := environment.NewEnclosedEnvironment(i.Environment)
+		environment.Define(stmt.CatchName.Lexeme, thrown)
+		i.executeBlock(stmt.CatchBody, environment)
+	}
 	return nil
 }
+
+// executeTry runs a try block and recovers whatever it throws. Runtime
+// errors are turned into Error instances; returns and anything that isn't
+// a script-level error keep unwinding.
+func (i *Interpreter) executeTry(body []ast.Stmt) (thrown interface{}, caught bool) {
+	depth := len(i.callStack)
+	defer func() {
+		if r := recover(); r != nil {
+			// Calls abandoned by the error are still on the stack.
+			i.callStack = i.callStack[:depth]
+			switch err := r.(type) {
+			case Throw:
+				thrown, caught = err.Value, true
+			case yaplErrors.RuntimeError:
+				thrown, caught = newErrorValue(err), true
+			default:
+				panic(r)
+			}
+		}
+	}()
+
+	i.executeBlock(body, environment.NewEnclosedEnvironment(i.Environment))
+	return nil, false
+}
+
+// executeFinally runs a finally block with any pending break or continue
+// put aside, and restores it afterwards unless the block started its own.
+func (i *Interpreter) executeFinally(body []ast.Stmt) {
+	breaking, continuing := i.abruptCompletion, i.continueException
+	i.abruptCompletion, i.continueException = false, false
+	i.executeBlock(body, environment.NewEnclosedEnvironment(i.Environment))
+	if !i.abruptCompletion && !i.continueException {
+		i.abruptCompletion, i.continueException = breaking, continuing
+	}
+}
@@ -0,0 +1,89 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+)
+
+// YaplList is the runtime representation of a list. Lists are shared by
+// reference, so assigning one to another variable doesn't copy it.
+type YaplList struct {
+	Elements []interface{}
+}
+
+// Get returns the element at index, raising a runtime error at bracket if
+// index isn't a valid position in the list.
+func (l *YaplList) Get(bracket token.Token, index interface{}) interface{} {
+	return l.Elements[l.position(bracket, index)]
+}
+
+func (l *YaplList) Set(bracket token.Token, index interface{}, value interface{}) {
+	l.Elements[l.position(bracket, index)] = value
+}
+
+func (l *YaplList) position(bracket token.Token, index interface{}) int {
+	number, ok := index.(float64)
+	if !ok || number != math.Trunc(number) {
+		panic(yaplErrors.RuntimeError{
+			Token:   bracket,
+			Message: "List index must be an integer.",
+		}.ThrowRuntimeError())
+	}
+	if number < 0 {
+		panic(yaplErrors.RuntimeError{
+			Token:   bracket,
+			Message: fmt.Sprintf("List index %s is negative.", stringify(number)),
+		}.ThrowRuntimeError())
+	}
+	if number >= float64(len(l.Elements)) {
+		panic(yaplErrors.RuntimeError{
+			Token:   bracket,
+			Message: fmt.Sprintf("List index %s out of bounds for length %d.", stringify(number), len(l.Elements)),
+		}.ThrowRuntimeError())
+	}
+	return int(number)
+}
+
+func (l *YaplList) String() string {
+	return l.format(nil)
+}
+
+// format prints the list inside the lists and maps in enclosing. A list
+// that contains itself, directly or not, is printed as [...] the second
+// time it is reached.
+func (l *YaplList) format(enclosing []interface{}) string {
+	if contains(enclosing, l) {
+		return "[...]"
+	}
+	enclosing = append(enclosing, l)
+	elements := make([]string, len(l.Elements))
+	for idx, element := range l.Elements {
+		elements[idx] = formatElement(element, enclosing)
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// formatElement stringifies a value held in the lists and maps in
+// enclosing.
+func formatElement(value interface{}, enclosing []interface{}) string {
+	switch value := value.(type) {
+	case *YaplList:
+		return value.format(enclosing)
+	case *YaplMap:
+		return value.format(enclosing)
+	}
+	return stringify(value)
+}
+
+func contains(containers []interface{}, container interface{}) bool {
+	for _, c := range containers {
+		if c == container {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+)
+
+// YaplMap is the runtime representation of a map. Keys are strings or
+// numbers and are iterated in the order they were first inserted. Like
+// lists, maps are shared by reference.
+type YaplMap struct {
+	keys    []interface{}
+	entries map[interface{}]interface{}
+}
+
+func NewYaplMap() *YaplMap {
+	return &YaplMap{
+		keys:    []interface{}{},
+		entries: make(map[interface{}]interface{}),
+	}
+}
+
+// Keys returns the keys in insertion order.
+func (m *YaplMap) Keys() []interface{} {
+	return append([]interface{}{}, m.keys...)
+}
+
+func (m *YaplMap) Len() int {
+	return len(m.keys)
+}
+
+func (m *YaplMap) Has(key interface{}) bool {
+	_, ok := m.entries[key]
+	return ok
+}
+
+func (m *YaplMap) Lookup(key interface{}) (interface{}, bool) {
+	value, ok := m.entries[key]
+	return value, ok
+}
+
+func (m *YaplMap) Put(key interface{}, value interface{}) {
+	if _, ok := m.entries[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.entries[key] = value
+}
+
+func (m *YaplMap) Delete(key interface{}) bool {
+	if _, ok := m.entries[key]; !ok {
+		return false
+	}
+	delete(m.entries, key)
+	for idx, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:idx], m.keys[idx+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Get returns the value stored under key, raising a runtime error at
+// bracket if the key is invalid or missing.
+func (m *YaplMap) Get(bracket token.Token, key interface{}) interface{} {
+	checkMapKey(bracket, key)
+	value, ok := m.entries[key]
+	if !ok {
+		panic(yaplErrors.RuntimeError{
+			Token:   bracket,
+			Message: fmt.Sprintf("Key '%s' not found in map.", stringify(key)),
+		}.ThrowRuntimeError())
+	}
+	return value
+}
+
+func (m *YaplMap) Set(bracket token.Token, key interface{}, value interface{}) {
+	checkMapKey(bracket, key)
+	m.Put(key, value)
+}
+
+func checkMapKey(tok token.Token, key interface{}) {
+	switch key := key.(type) {
+	case float64:
+		if !math.IsNaN(key) {
+			return
+		}
+		// NaN isn't equal to itself, so it could never be found again
+		panic(yaplErrors.RuntimeError{
+			Token:   tok,
+			Message: "Map keys can't be NaN.",
+		}.ThrowRuntimeError())
+	case string:
+		return
+	}
+	panic(yaplErrors.RuntimeError{
+		Token:   tok,
+		Message: "Map keys must be strings or numbers.",
+	}.ThrowRuntimeError())
+}
+
+func (m *YaplMap) String() string {
+	return m.format(nil)
+}
+
+// format is YaplList.format for maps, which print as {...} when reached
+// again.
+func (m *YaplMap) format(enclosing []interface{}) string {
+	if contains(enclosing, m) {
+		return "{...}"
+	}
+	enclosing = append(enclosing, m)
+	entries := make([]string, len(m.keys))
+	for idx, key := range m.keys {
+		entries[idx] = stringify(key) + ": " + formatElement(m.entries[key], enclosing)
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
@@ -0,0 +1,109 @@
+package interpreter
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+)
+
+// NativeFn is the Go side of a native function. A returned error becomes a
+// YAPL runtime error reported at the call site.
+type NativeFn func(interpreter *Interpreter, arguments []interface{}) (interface{}, error)
+
+// NativeFunction is a Go function exposed to scripts as a callable value.
+type NativeFunction struct {
+	Name     string
+	Params   int
+	Function NativeFn
+}
+
+var _ YaplCallable = (*NativeFunction)(nil)
+
+func (n *NativeFunction) Arity() int {
+	return n.Params
+}
+
+// Call runs the native, raising an error it returns as a runtime error at
+// the paren of the call.
+func (n *NativeFunction) Call(interpreter *Interpreter, arguments []interface{}) interface{} {
+	value, err := n.Function(interpreter, arguments)
+	if err != nil {
+		panic(yaplErrors.RuntimeError{
+			Token:   interpreter.callSite,
+			Message: err.Error(),
+		}.ThrowRuntimeError())
+	}
+	return value
+}
+
+func (n *NativeFunction) String() string {
+	return "<native fn>"
+}
+
+// DefineNative registers fn as a global function called name that takes
+// exactly arity arguments.
+func (i *Interpreter) DefineNative(name string, arity int, fn NativeFn) {
+	i.Globals.Define(name, &NativeFunction{
+		Name:     name,
+		Params:   arity,
+		Function: fn,
+	})
+}
+
+func (i *Interpreter) defineBuiltins() {
+	i.DefineNative("clock", 0, func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+		return float64(time.Now().UnixNano()) / float64(time.Second), nil
+	})
+	i.DefineNative("len", 1, func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+		switch v := arguments[0].(type) {
+		case string:
+			return float64(utf8.RuneCountInString(v)), nil
+		case *YaplList:
+			return float64(len(v.Elements)), nil
+		case *YaplMap:
+			return float64(v.Len()), nil
+		}
+		return nil, fmt.Errorf("Can't take len() of %s.", stringify(arguments[0]))
+	})
+	i.DefineNative("keys", 1, func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+		m, err := mapArgument("keys", arguments[0])
+		if err != nil {
+			return nil, err
+		}
+		return &YaplList{Elements: m.Keys()}, nil
+	})
+	i.DefineNative("values", 1, func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+		m, err := mapArgument("values", arguments[0])
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, 0, m.Len())
+		for _, key := range m.keys {
+			values = append(values, m.entries[key])
+		}
+		return &YaplList{Elements: values}, nil
+	})
+	i.DefineNative("has", 2, func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+		m, err := mapArgument("has", arguments[0])
+		if err != nil {
+			return nil, err
+		}
+		return m.Has(arguments[1]), nil
+	})
+	i.DefineNative("delete", 2, func(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+		m, err := mapArgument("delete", arguments[0])
+		if err != nil {
+			return nil, err
+		}
+		return m.Delete(arguments[1]), nil
+	})
+}
+
+func mapArgument(name string, argument interface{}) (*YaplMap, error) {
+	if m, ok := argument.(*YaplMap); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("%s() expects a map but got %s.", name, stringify(argument))
+}
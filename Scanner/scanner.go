@@ -5,6 +5,7 @@ import (
 	"github.com/shubhdevelop/YAPL/Token"
 	"github.com/shubhdevelop/YAPL/YaplErrors"
 	"strconv"
+	"unicode/utf8"
 )
 
 type Scanning interface {
@@ -12,13 +13,32 @@ type Scanning interface {
 }
 
 type Scanner struct {
-	Source  string
-	Tokens  []token.Token
-	start   int
-	current int
-	line    int
+	Source string
+	// File is the FileID of Source in the session's token.FileSet. It is
+	// copied onto every token so errors can quote the source.
+	File     token.FileID
+	Tokens   []token.Token
+	Reporter *yaplErrors.Reporter
+	// Trivia keeps comments as COMMENT tokens instead of dropping them, for
+	// tools such as the formatter that must reproduce the source. Whitespace
+	// can be recovered from the tokens' offsets.
+	Trivia bool
+	// start and current are byte offsets into Source. column counts the
+	// runes consumed so far on the current line; startLine and startColumn
+	// are where the token being scanned begins.
+	start       int
+	current     int
+	line        int
+	column      int
+	startLine   int
+	startColumn int
 }
 
+// UnterminatedString is the message reported for a string literal that runs
+// to the end of the source. The REPL looks for it to tell when a string
+// continues on the next line.
+const UnterminatedString = "Unterminated string."
+
 var KeywordMap = map[string]token.TokenType{
 	"and":      token.AND,
 	"class":    token.CLASS,
@@ -38,6 +58,10 @@ var KeywordMap = map[string]token.TokenType{
 	"while":    token.WHILE,
 	"break":    token.BREAK,
 	"continue": token.CONTINUE,
+	"try":      token.TRY,
+	"catch":    token.CATCH,
+	"finally":  token.FINALLY,
+	"throw":    token.THROW,
 }
 
 func (s *Scanner) isAtEnd() bool {
@@ -45,20 +69,41 @@ func (s *Scanner) isAtEnd() bool {
 }
 
 func (s *Scanner) advance() rune {
-	runes := []rune(s.Source)
-	ch := runes[s.current]
-	s.current++
+	ch, size := utf8.DecodeRuneInString(s.Source[s.current:])
+	s.current += size
+	s.column++
 	return ch
 }
 
-func (s *Scanner) addToken(t token.TokenType, literal interface{}) {
-	text := s.Source[s.start:s.current] // substring
-	tok := token.Token{
-		Type:    t,
-		Lexeme:  text,
-		Literal: literal,
-		Line:    s.line,
+// newline is called after consuming a '\n'.
+func (s *Scanner) newline() {
+	s.line++
+	s.column = 0
+}
+
+func (s *Scanner) beginToken() {
+	s.start = s.current
+	s.startLine = s.line
+	s.startColumn = s.column + 1
+}
+
+// span returns a token covering the text scanned since start, with no type
+// or literal yet.
+func (s *Scanner) span() token.Token {
+	return token.Token{
+		Lexeme: s.Source[s.start:s.current], // substring
+		Line:   s.startLine,
+		Column: s.startColumn,
+		Start:  s.start,
+		End:    s.current,
+		File:   s.File,
 	}
+}
+
+func (s *Scanner) addToken(t token.TokenType, literal interface{}) {
+	tok := s.span()
+	tok.Type = t
+	tok.Literal = literal
 	s.Tokens = append(s.Tokens, tok)
 }
 
@@ -70,10 +115,10 @@ func (s *Scanner) match(expected rune) bool {
 	if s.isAtEnd() {
 		return false
 	}
-	if runes := []rune(s.Source); runes[s.current] != expected {
+	if s.peek() != expected {
 		return false
 	}
-	s.current++
+	s.advance()
 	return true
 }
 
@@ -81,16 +126,20 @@ func (s *Scanner) peek() rune {
 	if s.isAtEnd() {
 		return '\000'
 	}
-	runes := []rune(s.Source)
-	return runes[s.current]
+	ch, _ := utf8.DecodeRuneInString(s.Source[s.current:])
+	return ch
 }
 
 func (s *Scanner) peekNext() rune {
-	if s.current+1 >= len(s.Source) {
+	if s.isAtEnd() {
+		return '\000'
+	}
+	_, size := utf8.DecodeRuneInString(s.Source[s.current:])
+	if s.current+size >= len(s.Source) {
 		return '\000'
 	}
-	runes := []rune(s.Source)
-	return runes[s.current+1]
+	ch, _ := utf8.DecodeRuneInString(s.Source[s.current+size:])
+	return ch
 }
 
 func (s *Scanner) isAlpha(c rune) bool {
@@ -106,13 +155,12 @@ func (s *Scanner) isAlphaNumeric(c rune) bool {
 
 func (s *Scanner) string() {
 	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
-			s.line++
+		if s.advance() == '\n' {
+			s.newline()
 		}
-		s.advance()
 	}
 	if s.isAtEnd() {
-		yaplErrors.ThrowNewError(s.line, "Unterminated string.")
+		s.Reporter.ErrorAt(s.span(), UnterminatedString)
 		return
 	}
 	s.advance()
@@ -140,7 +188,7 @@ func (s *Scanner) number() {
 	value := s.Source[s.start:s.current]
 	valueInFloat, err := strconv.ParseFloat(value, 64)
 	if err != nil {
-		yaplErrors.ThrowNewError(s.line, "Unexpected Numerical Value")
+		s.Reporter.ErrorAt(s.span(), "Unexpected Numerical Value")
 	}
 	s.addToken(token.NUMBER, valueInFloat)
 }
@@ -149,7 +197,7 @@ func (s *Scanner) identifier() {
 	for s.isAlphaNumeric(s.peek()) {
 		s.advance()
 	}
-	text := string([]rune(s.Source)[s.start:s.current])
+	text := s.Source[s.start:s.current]
 	tokenType, ok := KeywordMap[text]
 	if !ok {
 		s.addToken(token.IDENTIFIER, text)
@@ -169,8 +217,14 @@ func (s *Scanner) scanToken() {
 		s.addToken(token.LEFT_BRACE, nil)
 	case '}':
 		s.addToken(token.RIGHT_BRACE, nil)
+	case '[':
+		s.addToken(token.LEFT_BRACKET, nil)
+	case ']':
+		s.addToken(token.RIGHT_BRACKET, nil)
 	case ',':
 		s.addToken(token.COMMA, nil)
+	case ':':
+		s.addToken(token.COLON, nil)
 	case '.':
 		s.addToken(token.DOT, nil)
 	case '-':
@@ -211,6 +265,9 @@ func (s *Scanner) scanToken() {
 			for s.peek() != '\n' && !s.isAtEnd() {
 				s.advance()
 			}
+			if s.Trivia {
+				s.addToken(token.COMMENT, nil)
+			}
 		} else {
 			s.addToken(token.SLASH, nil)
 		}
@@ -219,7 +276,7 @@ func (s *Scanner) scanToken() {
 	case '\t':
 		// Ignore whitespace.
 	case '\n':
-		s.line++
+		s.newline()
 	case '"':
 		s.string()
 	default:
@@ -228,7 +285,7 @@ func (s *Scanner) scanToken() {
 		} else if s.isAlpha(c) {
 			s.identifier()
 		} else {
-			yaplErrors.ThrowNewError(s.line, "Unexpected Character Encountered")
+			s.Reporter.ErrorAt(s.span(), "Unexpected Character Encountered")
 		}
 	}
 }
@@ -237,11 +294,13 @@ func (s *Scanner) ScanTokens() ([]token.Token, error) {
 	if len(s.Source) == 0 {
 		return nil, errors.New("source is empty")
 	}
+	s.line = 1
 	for !s.isAtEnd() {
-		s.start = s.current
+		s.beginToken()
 		s.scanToken()
 
 	}
-	s.Tokens = append(s.Tokens, token.Token{token.EOF, "", nil, s.line})
+	s.beginToken()
+	s.addToken(token.EOF, nil)
 	return s.Tokens, nil
 }
@@ -0,0 +1,49 @@
+package token
+
+import "strings"
+
+// FileID identifies a source registered in a FileSet. The zero FileID is an
+// unregistered source, for tokens made up outside the scanner.
+type FileID int
+
+// File is a source that was scanned: a file, a REPL line or an embedded
+// snippet.
+type File struct {
+	Name   string
+	Source string
+}
+
+// FileSet holds every source scanned in one session so a token's FileID
+// can be turned back into its file name and text.
+type FileSet struct {
+	files []*File
+}
+
+// Add registers source under name and returns its FileID.
+func (s *FileSet) Add(name, source string) FileID {
+	s.files = append(s.files, &File{Name: name, Source: source})
+	return FileID(len(s.files))
+}
+
+// File returns the source registered as id, or nil if there is none. A nil
+// FileSet holds no sources.
+func (s *FileSet) File(id FileID) *File {
+	if s == nil || id <= 0 || int(id) > len(s.files) {
+		return nil
+	}
+	return s.files[id-1]
+}
+
+// LineAt returns the line of f containing the byte offset, and the offset
+// that line starts at.
+func (f *File) LineAt(offset int) (string, int) {
+	if offset > len(f.Source) {
+		offset = len(f.Source)
+	}
+	start := strings.LastIndexByte(f.Source[:offset], '\n') + 1
+	end := strings.IndexByte(f.Source[offset:], '\n')
+	if end == -1 {
+		return f.Source[start:], start
+	}
+	return f.Source[start : offset+end], start
+}
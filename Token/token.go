@@ -13,7 +13,10 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
 	COMMA
+	COLON
 	DOT
 	MINUS
 	PLUS
@@ -55,6 +58,13 @@ const (
 	WHILE
 	BREAK
 	CONTINUE
+	TRY
+	CATCH
+	FINALLY
+	THROW
+
+	// Trivia, only produced when Scanner.Trivia is set
+	COMMENT
 
 	// End of file
 	EOF
@@ -62,13 +72,15 @@ const (
 
 func (t TokenType) String() string {
 	return [...]string{
-		"LEFT_PAREN", "RIGHT_PAREN", "LEFT_BRACE", "RIGHT_BRACE",
-		"COMMA", "DOT", "MINUS", "PLUS", "SEMICOLON", "SLASH", "STAR",
+		"LEFT_PAREN", "RIGHT_PAREN", "LEFT_BRACE", "RIGHT_BRACE", "LEFT_BRACKET", "RIGHT_BRACKET",
+		"COMMA", "COLON", "DOT", "MINUS", "PLUS", "SEMICOLON", "SLASH", "STAR",
 		"BANG", "BANG_EQUAL", "EQUAL", "EQUAL_EQUAL", "GREATER", "GREATER_EQUAL",
 		"LESS", "LESS_EQUAL",
 		"IDENTIFIER", "STRING", "NUMBER",
 		"AND", "CLASS", "ELSE", "FALSE", "FUN", "FOR", "IF", "NIL", "OR",
 		"PRINT", "RETURN", "SUPER", "THIS", "TRUE", "VAR", "WHILE", "BREAK", "CONTINUE",
+		"TRY", "CATCH", "FINALLY", "THROW",
+		"COMMENT",
 		"EOF",
 	}[t]
 }
@@ -77,7 +89,11 @@ type Token struct {
 	Type    TokenType   // Enum we defined earlier
 	Lexeme  string      // Raw source text
 	Literal interface{} // Can hold string, number, etc.
-	Line    int         // Line number in source
+	Line    int         // Line number in source, starting at 1
+	Column  int         // Column of the first character, starting at 1
+	Start   int         // Byte offset of the lexeme in the source
+	End     int         // Byte offset just past the lexeme
+	File    FileID      // Source the token was scanned from
 }
 
 func (t Token) String() string {
@@ -2,39 +2,216 @@ package yaplErrors
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/shubhdevelop/YAPL/Token"
-	"github.com/shubhdevelop/YAPL/state"
 )
 
+// RuntimeError is an error raised while a program runs. Trace holds the
+// calls that were active when it happened, outermost first, so the last
+// frame is the one that failed. Errors raised outside any call have a
+// single <script> frame, or none if nobody recorded one.
 type RuntimeError struct {
 	Token   token.Token
 	Message string
+	Trace   []Frame
 }
 
-func (e RuntimeError) ThrowRuntimeError() string {
-	state.HadError = true
-	return fmt.Sprintf("%s\n[line %d]", e.Message, e.Token.Line)
+func (e RuntimeError) Error() string {
+	return e.Format(nil)
 }
 
-func report(line int, where, message string) {
-	fmt.Fprintf(os.Stderr, "[line %d] Error%s: %s\n", line, where, message)
+// Format is Error with the line the error happened on quoted from files,
+// with carets under the part that failed. It is the same as Error when
+// files doesn't hold that source.
+func (e RuntimeError) Format(files *token.FileSet) string {
+	location := fmt.Sprintf("line %d", e.Token.Line)
+	if e.Token.Column > 0 {
+		location += fmt.Sprintf(", column %d", e.Token.Column)
+	}
+	message := fmt.Sprintf("%s\n[%s]", e.Message, location)
+	if snippet := Snippet(files.File(e.Token.File), e.Token); snippet != "" {
+		message += "\n" + snippet
+	}
+	// A lone <script> frame says nothing the line above doesn't.
+	if len(e.Trace) > 1 {
+		message += "\n" + FormatTrace(e.Trace)
+	}
+	return message
 }
 
-func Error(token_p token.Token, message string) {
-	state.HadError = true
+// FormatError formats err with RuntimeError.Format if it is a
+// RuntimeError, quoting from files, and with its Error method otherwise.
+func FormatError(err error, files *token.FileSet) string {
+	if runtimeError, ok := err.(RuntimeError); ok {
+		return runtimeError.Format(files)
+	}
+	return err.Error()
+}
+
+// Frame is one active call in a RuntimeError's trace. Line and Column are
+// where that call was executing: the failing expression for the last frame
+// and the call into the next frame for the others. Column is zero when it
+// isn't known.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+	Column   int
+}
+
+func (f Frame) String() string {
+	location := fmt.Sprintf("line %d", f.Line)
+	if f.Column > 0 {
+		location += fmt.Sprintf(", column %d", f.Column)
+	}
+	if f.File != "" {
+		location = fmt.Sprintf("File %q, %s", f.File, location)
+	}
+	return fmt.Sprintf("%s, in %s", location, f.Function)
+}
+
+// maxRepeatedFrames is how many identical consecutive frames FormatTrace
+// prints before collapsing the rest, which keeps a stack overflow readable.
+const maxRepeatedFrames = 3
+
+// FormatTrace renders frames the way a traceback is printed, most recent
+// call last.
+func FormatTrace(frames []Frame) string {
+	var builder strings.Builder
+	builder.WriteString("Traceback (most recent call last):")
+	for idx := 0; idx < len(frames); {
+		repeats := 1
+		for idx+repeats < len(frames) && frames[idx+repeats] == frames[idx] {
+			repeats++
+		}
+		shown := repeats
+		if shown > maxRepeatedFrames {
+			shown = maxRepeatedFrames
+		}
+		for n := 0; n < shown; n++ {
+			builder.WriteString("\n  " + frames[idx].String())
+		}
+		if repeats > shown {
+			fmt.Fprintf(&builder, "\n  [Previous line repeated %d more times]", repeats-shown)
+		}
+		idx += repeats
+	}
+	return builder.String()
+}
+
+// ThrowRuntimeError returns the error ready to be panicked and recovered by
+// whoever is running the program.
+func (e RuntimeError) ThrowRuntimeError() RuntimeError {
+	return e
+}
+
+// SyntaxError is an error found before the program runs: by the scanner,
+// the parser, the resolver or the bytecode compiler.
+type SyntaxError struct {
+	Line    int
+	Column  int // zero when only the line is known
+	Where   string
+	Message string
+}
+
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("[line %d] Error%s: %s", e.Line, e.Where, e.Message)
+}
+
+// Reporter collects the SyntaxErrors found in one source. If Output is set
+// every error is also written to it as it is reported, followed by the
+// offending source line when Files holds the source it came from. A nil
+// *Reporter writes straight to os.Stderr and remembers nothing, so anything
+// that needs to know whether errors happened must create its own.
+type Reporter struct {
+	Output io.Writer
+	Files  *token.FileSet
+	Errors []error
+}
+
+func (r *Reporter) report(at token.Token, where, message string) SyntaxError {
+	err := SyntaxError{Line: at.Line, Column: at.Column, Where: where, Message: message}
+	if r == nil {
+		fmt.Fprintln(os.Stderr, err)
+		return err
+	}
+	r.Errors = append(r.Errors, err)
+	if r.Output != nil {
+		fmt.Fprintln(r.Output, err)
+		if snippet := Snippet(r.Files.File(at.File), at); snippet != "" {
+			fmt.Fprintln(r.Output, snippet)
+		}
+	}
+	return err
+}
+
+// Snippet quotes the line of file that at starts on, with carets under the
+// part of it at spans. It returns "" if file is nil or at has no position,
+// or if the line is blank, as it is for an error at the end of a file that
+// ends in a newline.
+func Snippet(file *token.File, at token.Token) string {
+	if file == nil || at.Line == 0 {
+		return ""
+	}
+	line, lineStart := file.LineAt(at.Start)
+	if strings.TrimSpace(line) == "" {
+		return ""
+	}
+	column := at.Start - lineStart
+	end := at.End - lineStart
+	if end > len(line) {
+		end = len(line)
+	}
+
+	var caret strings.Builder
+	for _, ch := range line[:column] {
+		// Keep tabs so the caret lines up however wide they are shown.
+		if ch == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	width := utf8.RuneCountInString(line[column:end])
+	if width < 1 {
+		width = 1
+	}
+	caret.WriteString(strings.Repeat("^", width))
+	return "    " + line + "\n    " + caret.String()
+}
+
+func (r *Reporter) HadError() bool {
+	return r != nil && len(r.Errors) > 0
+}
+
+// Error reports message at token_p and returns the SyntaxError it recorded.
+func (r *Reporter) Error(token_p token.Token, message string) SyntaxError {
 	// token_p because the it's clashing the token module name
 	// _p suggest the the parameter
 	if token_p.Type == token.EOF {
-		report(token_p.Line, " at end", message)
-	} else {
-		report(token_p.Line, " at '"+token_p.Lexeme+"'", message)
+		return r.report(token_p, " at end", message)
 	}
+	return r.report(token_p, " at '"+token_p.Lexeme+"'", message)
+}
 
+// ErrorAt reports message at the source span of at without quoting its
+// lexeme, for errors in text that isn't a whole token.
+func (r *Reporter) ErrorAt(at token.Token, message string) {
+	r.report(at, "", message)
+}
+
+func (r *Reporter) ThrowNewError(line int, message string) {
+	r.report(token.Token{Line: line}, "", message)
+}
+
+func Error(token_p token.Token, message string) {
+	(*Reporter)(nil).Error(token_p, message)
 }
 
 func ThrowNewError(line int, message string) {
-	report(line, "", message)
-	state.HadError = true
+	(*Reporter)(nil).ThrowNewError(line, message)
 }
@@ -12,6 +12,15 @@ type ExprVisitor interface {
     VisitUnaryExpr(expr Unary) interface{}
     VisitVariableExpr(expr Variable) interface{}
     VisitAssignExpr(expr Assign) interface{}
+    VisitCallExpr(expr Call) interface{}
+    VisitGetExpr(expr Get) interface{}
+    VisitSetExpr(expr Set) interface{}
+    VisitSuperExpr(expr Super) interface{}
+    VisitThisExpr(expr This) interface{}
+    VisitListExpr(expr List) interface{}
+    VisitMapExpr(expr Map) interface{}
+    VisitIndexExpr(expr Index) interface{}
+    VisitIndexSetExpr(expr IndexSet) interface{}
 }
 
 type Expr interface {
@@ -65,6 +74,7 @@ func (n Unary) Accept(visitor ExprVisitor) interface{} {
 
 type Variable struct {
     Name token.Token
+    Binding *Binding
 }
 
 func (n Variable) Accept(visitor ExprVisitor) interface{} {
@@ -74,9 +84,98 @@ func (n Variable) Accept(visitor ExprVisitor) interface{} {
 type Assign struct {
     Name token.Token
     Value Expr
+    Binding *Binding
 }
 
 func (n Assign) Accept(visitor ExprVisitor) interface{} {
     return visitor.VisitAssignExpr(n)
 }
 
+type Call struct {
+    Callee Expr
+    Paren token.Token
+    Arguments []Expr
+}
+
+func (n Call) Accept(visitor ExprVisitor) interface{} {
+    return visitor.VisitCallExpr(n)
+}
+
+type Get struct {
+    Object Expr
+    Name token.Token
+}
+
+func (n Get) Accept(visitor ExprVisitor) interface{} {
+    return visitor.VisitGetExpr(n)
+}
+
+type Set struct {
+    Object Expr
+    Name token.Token
+    Value Expr
+}
+
+func (n Set) Accept(visitor ExprVisitor) interface{} {
+    return visitor.VisitSetExpr(n)
+}
+
+type Super struct {
+    Keyword token.Token
+    Method token.Token
+    Binding *Binding
+}
+
+func (n Super) Accept(visitor ExprVisitor) interface{} {
+    return visitor.VisitSuperExpr(n)
+}
+
+type This struct {
+    Keyword token.Token
+    Binding *Binding
+}
+
+func (n This) Accept(visitor ExprVisitor) interface{} {
+    return visitor.VisitThisExpr(n)
+}
+
+type List struct {
+    Bracket token.Token
+    Elements []Expr
+}
+
+func (n List) Accept(visitor ExprVisitor) interface{} {
+    return visitor.VisitListExpr(n)
+}
+
+type Map struct {
+    Brace token.Token
+    Keys []Expr
+    Values []Expr
+}
+
+func (n Map) Accept(visitor ExprVisitor) interface{} {
+    return visitor.VisitMapExpr(n)
+}
+
+type Index struct {
+    Object Expr
+    Bracket token.Token
+    Index Expr
+}
+
+func (n Index) Accept(visitor ExprVisitor) interface{} {
+    return visitor.VisitIndexExpr(n)
+}
+
+type IndexSet struct {
+    Object Expr
+    Bracket token.Token
+    Index Expr
+    Value Expr
+}
+
+func (n IndexSet) Accept(visitor ExprVisitor) interface{} {
+    return visitor.VisitIndexSetExpr(n)
+}
+
@@ -0,0 +1,20 @@
+package ast
+
+// Binding records where the resolver found the variable an expression refers
+// to: Depth environments up from the current one, at position Slot. The
+// parser allocates one per Variable, Assign, This and Super node so the
+// resolver can fill it in even though nodes are passed around by value.
+type Binding struct {
+	Depth int
+	Slot  int
+}
+
+// NewBinding returns an unresolved binding. Variables the resolver leaves
+// unresolved are looked up by name in the global environment.
+func NewBinding() *Binding {
+	return &Binding{Depth: -1}
+}
+
+func (b *Binding) IsGlobal() bool {
+	return b == nil || b.Depth < 0
+}
@@ -0,0 +1,82 @@
+package ast
+
+import "github.com/shubhdevelop/YAPL/Token"
+
+// StmtToken returns the first token of stmt that the tree keeps, for tools
+// that report positions: the keyword or name a statement starts with, or
+// else the first token of its expression. ok is false when there is none,
+// as for an empty block.
+func StmtToken(stmt Stmt) (tok token.Token, ok bool) {
+	switch s := stmt.(type) {
+	case BlockStmt:
+		for _, inner := range s.Statement {
+			if tok, ok := StmtToken(inner); ok {
+				return tok, true
+			}
+		}
+		return token.Token{}, false
+	case ExpressionStmt:
+		return ExprToken(s.Expression)
+	case IfStmt:
+		return s.Keyword, true
+	case PrintStmt:
+		return s.Keyword, true
+	case VarStmt:
+		return s.Name, true
+	case WhileStmt:
+		return s.Keyword, true
+	case BreakStmt:
+		return s.Keyword, true
+	case ContinueStmt:
+		return s.Keyword, true
+	case FunctionStmt:
+		return s.Name, true
+	case ReturnStmt:
+		return s.Keyword, true
+	case ClassStmt:
+		return s.Name, true
+	case TryStmt:
+		return s.Keyword, true
+	case ThrowStmt:
+		return s.Keyword, true
+	}
+	return token.Token{}, false
+}
+
+// ExprToken returns the leftmost token of expr that the tree keeps. ok is
+// false for a literal, whose token the parser doesn't keep.
+func ExprToken(expr Expr) (tok token.Token, ok bool) {
+	switch e := expr.(type) {
+	case Binary:
+		return ExprToken(e.Left)
+	case Grouping:
+		return ExprToken(e.Expression)
+	case Logical:
+		return ExprToken(e.Left)
+	case Unary:
+		return e.Operator, true
+	case Variable:
+		return e.Name, true
+	case Assign:
+		return e.Name, true
+	case Call:
+		return ExprToken(e.Callee)
+	case Get:
+		return ExprToken(e.Object)
+	case Set:
+		return ExprToken(e.Object)
+	case Super:
+		return e.Keyword, true
+	case This:
+		return e.Keyword, true
+	case List:
+		return e.Bracket, true
+	case Map:
+		return e.Brace, true
+	case Index:
+		return ExprToken(e.Object)
+	case IndexSet:
+		return ExprToken(e.Object)
+	}
+	return token.Token{}, false
+}
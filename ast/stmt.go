@@ -13,6 +13,11 @@ type StmtVisitor interface {
     VisitWhileStmtStmt(stmt WhileStmt) interface{}
     VisitBreakStmtStmt(stmt BreakStmt) interface{}
     VisitContinueStmtStmt(stmt ContinueStmt) interface{}
+    VisitFunctionStmtStmt(stmt FunctionStmt) interface{}
+    VisitReturnStmtStmt(stmt ReturnStmt) interface{}
+    VisitClassStmtStmt(stmt ClassStmt) interface{}
+    VisitTryStmtStmt(stmt TryStmt) interface{}
+    VisitThrowStmtStmt(stmt ThrowStmt) interface{}
 }
 
 type Stmt interface {
@@ -36,6 +41,7 @@ func (n ExpressionStmt) Accept(visitor StmtVisitor) interface{} {
 }
 
 type IfStmt struct {
+    Keyword token.Token
     Condition Expr
     ThenBranch Stmt
     ElseBranch Stmt
@@ -46,6 +52,7 @@ func (n IfStmt) Accept(visitor StmtVisitor) interface{} {
 }
 
 type PrintStmt struct {
+    Keyword token.Token
     Expression Expr
 }
 
@@ -63,8 +70,10 @@ func (n VarStmt) Accept(visitor StmtVisitor) interface{} {
 }
 
 type WhileStmt struct {
+    Keyword token.Token
     Condition Expr
     Body Stmt
+    Increment Expr
 }
 
 func (n WhileStmt) Accept(visitor StmtVisitor) interface{} {
@@ -72,6 +81,7 @@ func (n WhileStmt) Accept(visitor StmtVisitor) interface{} {
 }
 
 type BreakStmt struct {
+    Keyword token.Token
 }
 
 func (n BreakStmt) Accept(visitor StmtVisitor) interface{} {
@@ -79,9 +89,60 @@ func (n BreakStmt) Accept(visitor StmtVisitor) interface{} {
 }
 
 type ContinueStmt struct {
+    Keyword token.Token
 }
 
 func (n ContinueStmt) Accept(visitor StmtVisitor) interface{} {
     return visitor.VisitContinueStmtStmt(n)
 }
 
+type FunctionStmt struct {
+    Name token.Token
+    Params []token.Token
+    Body []Stmt
+}
+
+func (n FunctionStmt) Accept(visitor StmtVisitor) interface{} {
+    return visitor.VisitFunctionStmtStmt(n)
+}
+
+type ReturnStmt struct {
+    Keyword token.Token
+    Value Expr
+}
+
+func (n ReturnStmt) Accept(visitor StmtVisitor) interface{} {
+    return visitor.VisitReturnStmtStmt(n)
+}
+
+type ClassStmt struct {
+    Name token.Token
+    Superclass *Variable
+    Methods []FunctionStmt
+}
+
+func (n ClassStmt) Accept(visitor StmtVisitor) interface{} {
+    return visitor.VisitClassStmtStmt(n)
+}
+
+type TryStmt struct {
+    Keyword token.Token
+    Body []Stmt
+    CatchName *token.Token
+    CatchBody []Stmt
+    FinallyBody []Stmt
+}
+
+func (n TryStmt) Accept(visitor StmtVisitor) interface{} {
+    return visitor.VisitTryStmtStmt(n)
+}
+
+type ThrowStmt struct {
+    Keyword token.Token
+    Value Expr
+}
+
+func (n ThrowStmt) Accept(visitor StmtVisitor) interface{} {
+    return visitor.VisitThrowStmtStmt(n)
+}
+
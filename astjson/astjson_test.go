@@ -0,0 +1,180 @@
+package astjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	interpreter "github.com/shubhdevelop/YAPL/Interpreter"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/ast"
+	"github.com/shubhdevelop/YAPL/frontend"
+	"github.com/shubhdevelop/YAPL/resolver"
+)
+
+const program = `
+var total = 0;
+fun add(a, b) { var sum = a + b; return sum; }
+class Counter {
+  init() { this.count = 0; }
+  bump() { this.count = this.count + 1; return this; }
+}
+class Loud < Counter {
+  bump() { print "bump"; return super.bump(); }
+}
+for (var i = 0; i < 3; i = i + 1) {
+  if (i == 1) continue;
+  total = add(total, i);
+}
+var m = {"list": [1, 2.5, "three", nil, true]};
+m["list"][0] = -total;
+try { throw m; } catch (e) { print e["list"]; } finally { print "done"; }
+print Loud().bump().bump().count;
+while (true) { break; }
+print total;
+`
+
+func parse(t *testing.T, source string) []ast.Stmt {
+	t.Helper()
+	result := frontend.Parse(source, frontend.Options{})
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors)
+	}
+	return result.Statements
+}
+
+func resolve(t *testing.T, statements []ast.Stmt) {
+	t.Helper()
+	reporter := &yaplErrors.Reporter{}
+	resolver.NewResolver(reporter).Resolve(statements)
+	if reporter.HadError() {
+		t.Fatal(reporter.Errors)
+	}
+}
+
+func run(t *testing.T, statements []ast.Stmt) string {
+	t.Helper()
+	var out bytes.Buffer
+	interpreter := interpreter.NewInterpreter()
+	interpreter.Stdout = &out
+	if _, err := interpreter.Execute(statements); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	statements := parse(t, program)
+	encoded, err := Encode(statements)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolve(t, decoded)
+	reencoded, err := Encode(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, reencoded) {
+		t.Errorf("encoding changed after a round trip:\n%s\n%s", encoded, reencoded)
+	}
+	if want, got := run(t, statements), run(t, decoded); got != want {
+		t.Errorf("decoded program printed %q, want %q", got, want)
+	}
+}
+
+// A binding edited to point at a scope that isn't there is recomputed
+// rather than followed.
+func TestDecodeIgnoresBindings(t *testing.T) {
+	encoded, err := Encode(parse(t, "var x = 1;\n{ var y = 2; print x + y; }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.Replace(string(encoded), `"binding":{"depth":0,"slot":0}`, `"binding":{"depth":3,"slot":9}`, 1)
+	if edited == string(encoded) {
+		t.Fatalf("no binding to edit in %s", encoded)
+	}
+	edited = strings.Replace(edited, `"type":"Variable","name":{"type":"IDENTIFIER","lexeme":"x"`, `"type":"Variable","binding":{"depth":3,"slot":9},"name":{"type":"IDENTIFIER","lexeme":"x"`, 1)
+	decoded, err := Decode([]byte(edited))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolve(t, decoded)
+	if got := run(t, decoded); got != "3\n" {
+		t.Fatalf("printed %q, want %q", got, "3\n")
+	}
+}
+
+// keyword is the JSON form of a keyword token on line 1.
+func keyword(tokenType, lexeme string) string {
+	return `{"type":"` + tokenType + `","lexeme":"` + lexeme + `","line":1,"column":1}`
+}
+
+// The parser rejects this, super, break and continue in the wrong place, so
+// a decoded tree, which never went through the parser, must be rejected when
+// it is resolved.
+func TestResolveRejectsDecodedMisplacedKeywords(t *testing.T) {
+	method := `{"type":"IDENTIFIER","lexeme":"m","line":1,"column":1}`
+	tests := []struct {
+		name      string
+		statement string
+		want      string
+	}{
+		{"super at the top level", `{"type":"ExpressionStmt","expression":{"type":"Super","keyword":` + keyword("SUPER", "super") + `,"method":` + method + `}}`, "Can't use 'super' outside of a class."},
+		{"super without a superclass", `{"type":"ClassStmt","name":` + method + `,"methods":[{"type":"FunctionStmt","name":` + method + `,"params":[],"body":[{"type":"ExpressionStmt","expression":{"type":"Super","keyword":` + keyword("SUPER", "super") + `,"method":` + method + `}}]}]}`, "Can't use 'super' in a class with no superclass."},
+		{"this at the top level", `{"type":"ExpressionStmt","expression":{"type":"This","keyword":` + keyword("THIS", "this") + `}}`, "Can't use 'this' outside of a class."},
+		{"break at the top level", `{"type":"BreakStmt","keyword":` + keyword("BREAK", "break") + `}`, "breakStatement can only exist inside valid iterator"},
+		{"continue at the top level", `{"type":"ContinueStmt","keyword":` + keyword("CONTINUE", "continue") + `}`, "continueStatemen can only exist inside valid iterator"},
+		{"break in a function in a loop", `{"type":"WhileStmt","keyword":` + keyword("WHILE", "while") + `,"condition":{"type":"Literal","value":true},"body":{"type":"FunctionStmt","name":` + method + `,"params":[],"body":[{"type":"BreakStmt","keyword":` + keyword("BREAK", "break") + `}]}}`, "breakStatement can only exist inside valid iterator"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoded, err := Decode([]byte(`{"version":3,"statements":[` + test.statement + `]}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			reporter := &yaplErrors.Reporter{}
+			resolver.NewResolver(reporter).Resolve(decoded)
+			if !reporter.HadError() || !strings.Contains(reporter.Errors[0].Error(), test.want) {
+				t.Fatalf("got errors %v, want %q", reporter.Errors, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsMalformedDocuments(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     string
+	}{
+		{"not JSON", `{"version":3,"statements":[`, "unexpected end of JSON input"},
+		{"version 1", `{"version":1,"statements":[]}`, "unsupported version 1, want 3"},
+		{"version 2", `{"version":2,"statements":[]}`, "unsupported version 2, want 3"},
+		{"no version", `{"statements":[]}`, "unsupported version 0, want 3"},
+		{"statement is not an object", `{"version":3,"statements":[42]}`, "statements[0]: expected a node object"},
+		{"no node type", `{"version":3,"statements":[{}]}`, "statements[0]: missing node type"},
+		{"unknown statement", `{"version":3,"statements":[{"type":"GotoStmt"}]}`, `statements[0]: unknown statement type "GotoStmt"`},
+		{"expression as a statement", `{"version":3,"statements":[{"type":"Literal","value":1}]}`, `statements[0]: unknown statement type "Literal"`},
+		{"unknown expression", `{"version":3,"statements":[{"type":"ExpressionStmt","expression":{"type":"Lambda"}}]}`, `statements[0].expression: unknown expression type "Lambda"`},
+		{"missing expression", `{"version":3,"statements":[{"type":"ExpressionStmt"}]}`, "statements[0].expression: missing expression"},
+		{"unknown token type", `{"version":3,"statements":[{"type":"BreakStmt","keyword":{"type":"GOTO"}}]}`, `statements[0].keyword: unknown token type "GOTO"`},
+		{"object literal", `{"version":3,"statements":[{"type":"ExpressionStmt","expression":{"type":"Literal","value":{}}}]}`, "statements[0].expression.value: literal must be null, a number, a string or a boolean"},
+		{"uneven map", `{"version":3,"statements":[{"type":"ExpressionStmt","expression":{"type":"Map","keys":[{"type":"Literal","value":1}],"values":[]}}]}`, "statements[0].expression: map has 1 keys but 0 values"},
+		{"superclass is not a variable", `{"version":3,"statements":[{"type":"ClassStmt","name":` + keyword("IDENTIFIER", "A") + `,"superclass":{"type":"Literal","value":1},"methods":[]}]}`, "statements[0].superclass: superclass must be a Variable"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements, err := Decode([]byte(test.document))
+			if err == nil {
+				t.Fatalf("decoded %v, want an error", statements)
+			}
+			if _, ok := err.(DecodeError); !ok || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("got %v, want a DecodeError containing %q", err, test.want)
+			}
+		})
+	}
+}
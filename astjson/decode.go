@@ -0,0 +1,327 @@
+package astjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/ast"
+)
+
+// tokenTypes maps each token.TokenType name back to its value.
+var tokenTypes = map[string]token.TokenType{}
+
+func init() {
+	for t := token.LEFT_PAREN; t <= token.EOF; t++ {
+		tokenTypes[t.String()] = t
+	}
+}
+
+// DecodeError is returned by Decode for JSON that is not a valid program.
+// Path says where in the document the problem is, e.g.
+// "statements[2].body[0].expression".
+type DecodeError struct {
+	Path    string
+	Message string
+}
+
+func (e DecodeError) Error() string {
+	if e.Path == "" {
+		return "astjson: " + e.Message
+	}
+	return fmt.Sprintf("astjson: %s: %s", e.Path, e.Message)
+}
+
+// Decode rebuilds a program from the JSON form written by Encode. Bindings
+// in the document are not trusted, since a hand-edited one could point at
+// a scope or slot that isn't there: every node gets an unresolved binding,
+// as from the parser, and the result must be resolved before it runs.
+// Resolving also rejects this, super, break and continue where the parser
+// would have.
+func Decode(data []byte) (stmts []ast.Stmt, err error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, DecodeError{Message: err.Error()}
+	}
+	if doc.Version != Version {
+		return nil, DecodeError{Message: fmt.Sprintf("unsupported version %d, want %d", doc.Version, Version)}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			decodeErr, ok := r.(DecodeError)
+			if !ok {
+				panic(r)
+			}
+			stmts, err = nil, decodeErr
+		}
+	}()
+	d := &decoder{}
+	stmts = []ast.Stmt{}
+	for idx, raw := range doc.Statements {
+		stmts = append(stmts, d.stmt(fmt.Sprintf("statements[%d]", idx), raw))
+	}
+	return stmts, nil
+}
+
+// decoder walks the JSON form. It panics with a DecodeError at the first
+// problem, which Decode recovers.
+type decoder struct{}
+
+func (d *decoder) fail(path, format string, args ...interface{}) {
+	panic(DecodeError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// object decodes a node into its fields and type name.
+func (d *decoder) object(path string, raw json.RawMessage) (map[string]json.RawMessage, string) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		d.fail(path, "expected a node object")
+	}
+	var nodeType string
+	if err := json.Unmarshal(fields["type"], &nodeType); err != nil {
+		d.fail(path, "missing node type")
+	}
+	return fields, nodeType
+}
+
+// expr decodes a required expression.
+func (d *decoder) expr(path string, raw json.RawMessage) ast.Expr {
+	if isNull(raw) {
+		d.fail(path, "missing expression")
+	}
+	return d.optionalExpr(path, raw)
+}
+
+func (d *decoder) optionalExpr(path string, raw json.RawMessage) ast.Expr {
+	if isNull(raw) {
+		return nil
+	}
+	f, nodeType := d.object(path, raw)
+	field := func(name string) (string, json.RawMessage) {
+		return path + "." + name, f[name]
+	}
+	switch nodeType {
+	case "Binary":
+		return ast.Binary{Left: d.expr(field("left")), Operator: d.token(field("operator")), Right: d.expr(field("right"))}
+	case "Grouping":
+		return ast.Grouping{Expression: d.expr(field("expression"))}
+	case "Literal":
+		return ast.Literal{Value: d.literal(field("value"))}
+	case "Logical":
+		return ast.Logical{Left: d.expr(field("left")), Operator: d.token(field("operator")), Right: d.expr(field("right"))}
+	case "Unary":
+		return ast.Unary{Operator: d.token(field("operator")), Right: d.expr(field("right"))}
+	case "Variable":
+		return d.variable(path, f)
+	case "Assign":
+		return ast.Assign{Name: d.token(field("name")), Value: d.expr(field("value")), Binding: ast.NewBinding()}
+	case "Call":
+		return ast.Call{Callee: d.expr(field("callee")), Paren: d.token(field("paren")), Arguments: d.exprs(field("arguments"))}
+	case "Get":
+		return ast.Get{Object: d.expr(field("object")), Name: d.token(field("name"))}
+	case "Set":
+		return ast.Set{Object: d.expr(field("object")), Name: d.token(field("name")), Value: d.expr(field("value"))}
+	case "Super":
+		return ast.Super{Keyword: d.token(field("keyword")), Method: d.token(field("method")), Binding: ast.NewBinding()}
+	case "This":
+		return ast.This{Keyword: d.token(field("keyword")), Binding: ast.NewBinding()}
+	case "List":
+		return ast.List{Bracket: d.token(field("bracket")), Elements: d.exprs(field("elements"))}
+	case "Map":
+		keys, values := d.exprs(field("keys")), d.exprs(field("values"))
+		if len(keys) != len(values) {
+			d.fail(path, "map has %d keys but %d values", len(keys), len(values))
+		}
+		return ast.Map{Brace: d.token(field("brace")), Keys: keys, Values: values}
+	case "Index":
+		return ast.Index{Object: d.expr(field("object")), Bracket: d.token(field("bracket")), Index: d.expr(field("index"))}
+	case "IndexSet":
+		return ast.IndexSet{Object: d.expr(field("object")), Bracket: d.token(field("bracket")), Index: d.expr(field("index")), Value: d.expr(field("value"))}
+	}
+	d.fail(path, "unknown expression type %q", nodeType)
+	return nil
+}
+
+func (d *decoder) variable(path string, f map[string]json.RawMessage) ast.Variable {
+	return ast.Variable{Name: d.token(path+".name", f["name"]), Binding: ast.NewBinding()}
+}
+
+// exprs decodes a list of expressions, keeping null as nil.
+func (d *decoder) exprs(path string, raw json.RawMessage) []ast.Expr {
+	if isNull(raw) {
+		return nil
+	}
+	items := d.list(path, raw)
+	exprs := make([]ast.Expr, len(items))
+	for idx, item := range items {
+		exprs[idx] = d.expr(fmt.Sprintf("%s[%d]", path, idx), item)
+	}
+	return exprs
+}
+
+// stmt decodes a required statement.
+func (d *decoder) stmt(path string, raw json.RawMessage) ast.Stmt {
+	if isNull(raw) {
+		d.fail(path, "missing statement")
+	}
+	return d.optionalStmt(path, raw)
+}
+
+func (d *decoder) optionalStmt(path string, raw json.RawMessage) ast.Stmt {
+	if isNull(raw) {
+		return nil
+	}
+	f, nodeType := d.object(path, raw)
+	field := func(name string) (string, json.RawMessage) {
+		return path + "." + name, f[name]
+	}
+	switch nodeType {
+	case "BlockStmt":
+		return ast.BlockStmt{Statement: d.stmts(field("statements"))}
+	case "ExpressionStmt":
+		return ast.ExpressionStmt{Expression: d.expr(field("expression"))}
+	case "IfStmt":
+		return ast.IfStmt{Keyword: d.token(field("keyword")), Condition: d.expr(field("condition")), ThenBranch: d.stmt(field("thenBranch")), ElseBranch: d.optionalStmt(field("elseBranch"))}
+	case "PrintStmt":
+		return ast.PrintStmt{Keyword: d.token(field("keyword")), Expression: d.expr(field("expression"))}
+	case "VarStmt":
+		return ast.VarStmt{Name: d.token(field("name")), Initializer: d.optionalExpr(field("initializer"))}
+	case "WhileStmt":
+		return ast.WhileStmt{Keyword: d.token(field("keyword")), Condition: d.expr(field("condition")), Body: d.stmt(field("body")), Increment: d.optionalExpr(field("increment"))}
+	case "BreakStmt":
+		return ast.BreakStmt{Keyword: d.token(field("keyword"))}
+	case "ContinueStmt":
+		return ast.ContinueStmt{Keyword: d.token(field("keyword"))}
+	case "FunctionStmt":
+		return d.function(path, f)
+	case "ReturnStmt":
+		return ast.ReturnStmt{Keyword: d.token(field("keyword")), Value: d.optionalExpr(field("value"))}
+	case "ClassStmt":
+		class := ast.ClassStmt{Name: d.token(field("name"))}
+		if path, raw := field("superclass"); !isNull(raw) {
+			superclass, ok := d.expr(path, raw).(ast.Variable)
+			if !ok {
+				d.fail(path, "superclass must be a Variable")
+			}
+			class.Superclass = &superclass
+		}
+		path, raw := field("methods")
+		for idx, method := range d.list(path, raw) {
+			methodPath := fmt.Sprintf("%s[%d]", path, idx)
+			fields, nodeType := d.object(methodPath, method)
+			if nodeType != "FunctionStmt" {
+				d.fail(methodPath, "method must be a FunctionStmt")
+			}
+			class.Methods = append(class.Methods, d.function(methodPath, fields))
+		}
+		return class
+	case "TryStmt":
+		try := ast.TryStmt{
+			Keyword:     d.token(field("keyword")),
+			Body:        d.stmts(field("body")),
+			CatchBody:   d.optionalStmts(field("catchBody")),
+			FinallyBody: d.optionalStmts(field("finallyBody")),
+		}
+		if path, raw := field("catchName"); !isNull(raw) {
+			name := d.token(path, raw)
+			try.CatchName = &name
+		}
+		return try
+	case "ThrowStmt":
+		return ast.ThrowStmt{Keyword: d.token(field("keyword")), Value: d.expr(field("value"))}
+	}
+	d.fail(path, "unknown statement type %q", nodeType)
+	return nil
+}
+
+func (d *decoder) function(path string, f map[string]json.RawMessage) ast.FunctionStmt {
+	function := ast.FunctionStmt{
+		Name: d.token(path+".name", f["name"]),
+		Body: d.stmts(path+".body", f["body"]),
+	}
+	for idx, param := range d.list(path+".params", f["params"]) {
+		function.Params = append(function.Params, d.token(fmt.Sprintf("%s.params[%d]", path, idx), param))
+	}
+	return function
+}
+
+// list decodes a JSON array, treating null as empty.
+func (d *decoder) list(path string, raw json.RawMessage) []json.RawMessage {
+	var items []json.RawMessage
+	if isNull(raw) {
+		return nil
+	}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		d.fail(path, "expected a list")
+	}
+	return items
+}
+
+// stmts decodes a required list of statements.
+func (d *decoder) stmts(path string, raw json.RawMessage) []ast.Stmt {
+	if isNull(raw) {
+		d.fail(path, "missing list of statements")
+	}
+	return d.optionalStmts(path, raw)
+}
+
+// optionalStmts decodes a list of statements, keeping null as nil, since a
+// nil FinallyBody means there is no finally clause.
+func (d *decoder) optionalStmts(path string, raw json.RawMessage) []ast.Stmt {
+	if isNull(raw) {
+		return nil
+	}
+	stmts := []ast.Stmt{}
+	for idx, item := range d.list(path, raw) {
+		stmts = append(stmts, d.stmt(fmt.Sprintf("%s[%d]", path, idx), item))
+	}
+	return stmts
+}
+
+func (d *decoder) token(path string, raw json.RawMessage) token.Token {
+	var tok jsonToken
+	if isNull(raw) || json.Unmarshal(raw, &tok) != nil {
+		d.fail(path, "expected a token")
+	}
+	tokenType, ok := tokenTypes[tok.Type]
+	if !ok {
+		d.fail(path, "unknown token type %q", tok.Type)
+	}
+	return token.Token{
+		Type:    tokenType,
+		Lexeme:  tok.Lexeme,
+		Literal: d.checkLiteral(path+".literal", tok.Literal),
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Start:   tok.Start,
+		End:     tok.End,
+	}
+}
+
+// literal decodes a literal value: nil, a float64, a string or a bool, the
+// types the scanner produces.
+func (d *decoder) literal(path string, raw json.RawMessage) interface{} {
+	if isNull(raw) {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		d.fail(path, "invalid literal")
+	}
+	return d.checkLiteral(path, value)
+}
+
+func (d *decoder) checkLiteral(path string, value interface{}) interface{} {
+	switch value.(type) {
+	case nil, float64, string, bool:
+		return value
+	}
+	d.fail(path, "literal must be null, a number, a string or a boolean")
+	return nil
+}
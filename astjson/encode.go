@@ -0,0 +1,275 @@
+// Package astjson converts syntax trees to and from JSON, so tools written
+// in other languages can read YAPL programs and parsed programs can be
+// cached.
+//
+// A document is {"version": 3, "statements": [...]}. Every node is an
+// object whose "type" is the name of its Go type in package ast ("Binary",
+// "WhileStmt", ...) and whose other keys are its fields in lower camel
+// case. Child nodes are nested objects, lists of them are arrays and a
+// missing optional child is null. Tokens are objects with "type" (the
+// token.TokenType name), "lexeme", "literal", "line", "column", "start" and
+// "end". Variable, Assign, Super and This nodes have a "binding" with the
+// resolver's "depth" and "slot" when the tree has been resolved; it is
+// there for other tools, and Decode ignores it.
+package astjson
+
+import (
+	"encoding/json"
+
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/ast"
+)
+
+// Version is the schema version Encode writes and Decode accepts. Version 2
+// added the "keyword" token to IfStmt, PrintStmt, WhileStmt, BreakStmt and
+// ContinueStmt, and version 3 the "increment" of a WhileStmt made from a
+// for loop.
+const Version = 3
+
+// document is the top level of the JSON form.
+type document struct {
+	Version    int               `json:"version"`
+	Statements []json.RawMessage `json:"statements"`
+}
+
+// jsonToken is the JSON form of a token.Token. The file a token came from
+// is not kept.
+type jsonToken struct {
+	Type    string      `json:"type"`
+	Lexeme  string      `json:"lexeme"`
+	Literal interface{} `json:"literal"`
+	Line    int         `json:"line"`
+	Column  int         `json:"column"`
+	Start   int         `json:"start"`
+	End     int         `json:"end"`
+}
+
+type jsonBinding struct {
+	Depth int `json:"depth"`
+	Slot  int `json:"slot"`
+}
+
+// node is the JSON form of an AST node. encoding/json writes its keys in
+// sorted order, so the output for a given tree never changes.
+type node map[string]interface{}
+
+// Encode returns the JSON form of a program.
+func Encode(stmts []ast.Stmt) ([]byte, error) {
+	e := &encoder{}
+	doc := document{Version: Version, Statements: []json.RawMessage{}}
+	for _, stmt := range stmts {
+		data, err := json.Marshal(e.stmt(stmt))
+		if err != nil {
+			return nil, err
+		}
+		doc.Statements = append(doc.Statements, data)
+	}
+	return json.Marshal(doc)
+}
+
+// encoder builds the JSON form of each node it visits.
+type encoder struct{}
+
+var _ ast.ExprVisitor = (*encoder)(nil)
+var _ ast.StmtVisitor = (*encoder)(nil)
+
+func (e *encoder) expr(expr ast.Expr) interface{} {
+	if expr == nil {
+		return nil
+	}
+	return expr.Accept(e)
+}
+
+func (e *encoder) exprs(exprs []ast.Expr) interface{} {
+	if exprs == nil {
+		return nil
+	}
+	nodes := make([]interface{}, len(exprs))
+	for idx, expr := range exprs {
+		nodes[idx] = e.expr(expr)
+	}
+	return nodes
+}
+
+func (e *encoder) stmt(stmt ast.Stmt) interface{} {
+	if stmt == nil {
+		return nil
+	}
+	return stmt.Accept(e)
+}
+
+// stmts keeps the difference between a nil and an empty list, since a nil
+// FinallyBody means there is no finally clause.
+func (e *encoder) stmts(stmts []ast.Stmt) interface{} {
+	if stmts == nil {
+		return nil
+	}
+	nodes := make([]interface{}, len(stmts))
+	for idx, stmt := range stmts {
+		nodes[idx] = e.stmt(stmt)
+	}
+	return nodes
+}
+
+func (e *encoder) token(tok token.Token) jsonToken {
+	return jsonToken{
+		Type:    tok.Type.String(),
+		Lexeme:  tok.Lexeme,
+		Literal: tok.Literal,
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Start:   tok.Start,
+		End:     tok.End,
+	}
+}
+
+func (e *encoder) tokens(tokens []token.Token) []jsonToken {
+	encoded := make([]jsonToken, len(tokens))
+	for idx, tok := range tokens {
+		encoded[idx] = e.token(tok)
+	}
+	return encoded
+}
+
+// withBinding adds binding to n if the resolver has filled it in.
+func (e *encoder) withBinding(n node, binding *ast.Binding) node {
+	if !binding.IsGlobal() {
+		n["binding"] = jsonBinding{Depth: binding.Depth, Slot: binding.Slot}
+	}
+	return n
+}
+
+func (e *encoder) VisitBinaryExpr(expr ast.Binary) interface{} {
+	return node{"type": "Binary", "left": e.expr(expr.Left), "operator": e.token(expr.Operator), "right": e.expr(expr.Right)}
+}
+
+func (e *encoder) VisitGroupingExpr(expr ast.Grouping) interface{} {
+	return node{"type": "Grouping", "expression": e.expr(expr.Expression)}
+}
+
+func (e *encoder) VisitLiteralExpr(expr ast.Literal) interface{} {
+	return node{"type": "Literal", "value": expr.Value}
+}
+
+func (e *encoder) VisitLogicalExpr(expr ast.Logical) interface{} {
+	return node{"type": "Logical", "left": e.expr(expr.Left), "operator": e.token(expr.Operator), "right": e.expr(expr.Right)}
+}
+
+func (e *encoder) VisitUnaryExpr(expr ast.Unary) interface{} {
+	return node{"type": "Unary", "operator": e.token(expr.Operator), "right": e.expr(expr.Right)}
+}
+
+func (e *encoder) VisitVariableExpr(expr ast.Variable) interface{} {
+	return e.withBinding(node{"type": "Variable", "name": e.token(expr.Name)}, expr.Binding)
+}
+
+func (e *encoder) VisitAssignExpr(expr ast.Assign) interface{} {
+	return e.withBinding(node{"type": "Assign", "name": e.token(expr.Name), "value": e.expr(expr.Value)}, expr.Binding)
+}
+
+func (e *encoder) VisitCallExpr(expr ast.Call) interface{} {
+	return node{"type": "Call", "callee": e.expr(expr.Callee), "paren": e.token(expr.Paren), "arguments": e.exprs(expr.Arguments)}
+}
+
+func (e *encoder) VisitGetExpr(expr ast.Get) interface{} {
+	return node{"type": "Get", "object": e.expr(expr.Object), "name": e.token(expr.Name)}
+}
+
+func (e *encoder) VisitSetExpr(expr ast.Set) interface{} {
+	return node{"type": "Set", "object": e.expr(expr.Object), "name": e.token(expr.Name), "value": e.expr(expr.Value)}
+}
+
+func (e *encoder) VisitSuperExpr(expr ast.Super) interface{} {
+	return e.withBinding(node{"type": "Super", "keyword": e.token(expr.Keyword), "method": e.token(expr.Method)}, expr.Binding)
+}
+
+func (e *encoder) VisitThisExpr(expr ast.This) interface{} {
+	return e.withBinding(node{"type": "This", "keyword": e.token(expr.Keyword)}, expr.Binding)
+}
+
+func (e *encoder) VisitListExpr(expr ast.List) interface{} {
+	return node{"type": "List", "bracket": e.token(expr.Bracket), "elements": e.exprs(expr.Elements)}
+}
+
+func (e *encoder) VisitMapExpr(expr ast.Map) interface{} {
+	return node{"type": "Map", "brace": e.token(expr.Brace), "keys": e.exprs(expr.Keys), "values": e.exprs(expr.Values)}
+}
+
+func (e *encoder) VisitIndexExpr(expr ast.Index) interface{} {
+	return node{"type": "Index", "object": e.expr(expr.Object), "bracket": e.token(expr.Bracket), "index": e.expr(expr.Index)}
+}
+
+func (e *encoder) VisitIndexSetExpr(expr ast.IndexSet) interface{} {
+	return node{"type": "IndexSet", "object": e.expr(expr.Object), "bracket": e.token(expr.Bracket), "index": e.expr(expr.Index), "value": e.expr(expr.Value)}
+}
+
+func (e *encoder) VisitBlockStmtStmt(stmt ast.BlockStmt) interface{} {
+	return node{"type": "BlockStmt", "statements": e.stmts(stmt.Statement)}
+}
+
+func (e *encoder) VisitExpressionStmtStmt(stmt ast.ExpressionStmt) interface{} {
+	return node{"type": "ExpressionStmt", "expression": e.expr(stmt.Expression)}
+}
+
+func (e *encoder) VisitIfStmtStmt(stmt ast.IfStmt) interface{} {
+	return node{"type": "IfStmt", "keyword": e.token(stmt.Keyword), "condition": e.expr(stmt.Condition), "thenBranch": e.stmt(stmt.ThenBranch), "elseBranch": e.stmt(stmt.ElseBranch)}
+}
+
+func (e *encoder) VisitPrintStmtStmt(stmt ast.PrintStmt) interface{} {
+	return node{"type": "PrintStmt", "keyword": e.token(stmt.Keyword), "expression": e.expr(stmt.Expression)}
+}
+
+func (e *encoder) VisitVarStmtStmt(stmt ast.VarStmt) interface{} {
+	return node{"type": "VarStmt", "name": e.token(stmt.Name), "initializer": e.expr(stmt.Initializer)}
+}
+
+func (e *encoder) VisitWhileStmtStmt(stmt ast.WhileStmt) interface{} {
+	return node{"type": "WhileStmt", "keyword": e.token(stmt.Keyword), "condition": e.expr(stmt.Condition), "body": e.stmt(stmt.Body), "increment": e.expr(stmt.Increment)}
+}
+
+func (e *encoder) VisitBreakStmtStmt(stmt ast.BreakStmt) interface{} {
+	return node{"type": "BreakStmt", "keyword": e.token(stmt.Keyword)}
+}
+
+func (e *encoder) VisitContinueStmtStmt(stmt ast.ContinueStmt) interface{} {
+	return node{"type": "ContinueStmt", "keyword": e.token(stmt.Keyword)}
+}
+
+func (e *encoder) VisitFunctionStmtStmt(stmt ast.FunctionStmt) interface{} {
+	return node{"type": "FunctionStmt", "name": e.token(stmt.Name), "params": e.tokens(stmt.Params), "body": e.stmts(stmt.Body)}
+}
+
+func (e *encoder) VisitReturnStmtStmt(stmt ast.ReturnStmt) interface{} {
+	return node{"type": "ReturnStmt", "keyword": e.token(stmt.Keyword), "value": e.expr(stmt.Value)}
+}
+
+func (e *encoder) VisitClassStmtStmt(stmt ast.ClassStmt) interface{} {
+	var superclass interface{}
+	if stmt.Superclass != nil {
+		superclass = e.expr(*stmt.Superclass)
+	}
+	methods := make([]interface{}, len(stmt.Methods))
+	for idx, method := range stmt.Methods {
+		methods[idx] = e.stmt(method)
+	}
+	return node{"type": "ClassStmt", "name": e.token(stmt.Name), "superclass": superclass, "methods": methods}
+}
+
+func (e *encoder) VisitTryStmtStmt(stmt ast.TryStmt) interface{} {
+	var catchName interface{}
+	if stmt.CatchName != nil {
+		catchName = e.token(*stmt.CatchName)
+	}
+	return node{
+		"type":        "TryStmt",
+		"keyword":     e.token(stmt.Keyword),
+		"body":        e.stmts(stmt.Body),
+		"catchName":   catchName,
+		"catchBody":   e.stmts(stmt.CatchBody),
+		"finallyBody": e.stmts(stmt.FinallyBody),
+	}
+}
+
+func (e *encoder) VisitThrowStmtStmt(stmt ast.ThrowStmt) interface{} {
+	return node{"type": "ThrowStmt", "keyword": e.token(stmt.Keyword), "value": e.expr(stmt.Value)}
+}
@@ -0,0 +1,112 @@
+package bytecode
+
+import "github.com/shubhdevelop/YAPL/Token"
+
+// OpCode is a single VM instruction. Operands, if any, follow the opcode in
+// the chunk: constant indices and jump offsets are two bytes (big endian),
+// local slots, upvalue indices and argument counts are one byte.
+type OpCode byte
+
+const (
+	OP_CONSTANT OpCode = iota
+	OP_NIL
+	OP_TRUE
+	OP_FALSE
+	OP_POP
+	OP_GET_LOCAL
+	OP_SET_LOCAL
+	OP_GET_GLOBAL
+	OP_DEFINE_GLOBAL
+	OP_SET_GLOBAL
+	OP_GET_UPVALUE
+	OP_SET_UPVALUE
+	OP_GET_PROPERTY
+	OP_SET_PROPERTY
+	OP_GET_SUPER
+	OP_EQUAL
+	OP_GREATER
+	OP_LESS
+	OP_ADD
+	OP_SUBTRACT
+	OP_MULTIPLY
+	OP_DIVIDE
+	OP_NOT
+	OP_NEGATE
+	OP_PRINT
+	OP_JUMP
+	OP_JUMP_IF_FALSE
+	OP_LOOP
+	OP_CALL
+	OP_CLOSURE
+	OP_CLOSE_UPVALUE
+	OP_RETURN
+	OP_CLASS
+	OP_INHERIT
+	OP_METHOD
+	OP_BUILD_LIST
+	OP_BUILD_MAP
+	OP_GET_INDEX
+	OP_SET_INDEX
+	OP_TRY
+	OP_END_TRY
+	OP_THROW
+)
+
+func (op OpCode) String() string {
+	return [...]string{
+		"OP_CONSTANT", "OP_NIL", "OP_TRUE", "OP_FALSE", "OP_POP",
+		"OP_GET_LOCAL", "OP_SET_LOCAL", "OP_GET_GLOBAL", "OP_DEFINE_GLOBAL", "OP_SET_GLOBAL",
+		"OP_GET_UPVALUE", "OP_SET_UPVALUE", "OP_GET_PROPERTY", "OP_SET_PROPERTY", "OP_GET_SUPER",
+		"OP_EQUAL", "OP_GREATER", "OP_LESS",
+		"OP_ADD", "OP_SUBTRACT", "OP_MULTIPLY", "OP_DIVIDE", "OP_NOT", "OP_NEGATE",
+		"OP_PRINT", "OP_JUMP", "OP_JUMP_IF_FALSE", "OP_LOOP", "OP_CALL",
+		"OP_CLOSURE", "OP_CLOSE_UPVALUE", "OP_RETURN",
+		"OP_CLASS", "OP_INHERIT", "OP_METHOD",
+		"OP_BUILD_LIST", "OP_BUILD_MAP", "OP_GET_INDEX", "OP_SET_INDEX",
+		"OP_TRY", "OP_END_TRY", "OP_THROW",
+	}[op]
+}
+
+// Chunk is a compiled sequence of instructions together with the constants
+// they reference. Positions holds the source position of every byte in Code
+// so runtime errors can report and quote where they happened.
+type Chunk struct {
+	Code      []byte
+	Constants []Value
+	Positions []Position
+}
+
+// Position is the part of a token a byte of code was compiled from.
+type Position struct {
+	File   token.FileID
+	Line   int
+	Column int
+	Start  int
+	End    int
+}
+
+// Token returns a token with the position p was recorded from.
+func (p Position) Token() token.Token {
+	return token.Token{File: p.File, Line: p.Line, Column: p.Column, Start: p.Start, End: p.End}
+}
+
+func (c *Chunk) Write(b byte, at token.Token) {
+	c.Code = append(c.Code, b)
+	c.Positions = append(c.Positions, Position{
+		File:   at.File,
+		Line:   at.Line,
+		Column: at.Column,
+		Start:  at.Start,
+		End:    at.End,
+	})
+}
+
+func (c *Chunk) WriteOp(op OpCode, at token.Token) {
+	c.Write(byte(op), at)
+}
+
+// AddConstant appends value to the constants table and returns its index.
+func (c *Chunk) AddConstant(value Value) int {
+	c.Constants = append(c.Constants, value)
+	return len(c.Constants) - 1
+}
@@ -0,0 +1,196 @@
+package bytecode
+
+import (
+	"strings"
+
+	"github.com/shubhdevelop/YAPL/Token"
+)
+
+// Function is a compiled function body. The top-level script is compiled to
+// a Function with an empty Name.
+type Function struct {
+	Arity        int
+	UpvalueCount int
+	Chunk        Chunk
+	Name         string
+}
+
+func (f *Function) String() string {
+	if f.Name == "" {
+		return "<script>"
+	}
+	return "<fn " + f.Name + ">"
+}
+
+// Upvalue is a variable captured by a closure. While the variable is still
+// on the VM stack Location points at its slot; once the slot goes out of
+// scope the value is copied into Closed and Location is redirected to it.
+type Upvalue struct {
+	Location *Value
+	Closed   Value
+	Slot     int
+	Next     *Upvalue
+}
+
+// Closure pairs a Function with the upvalues it captured when it was created.
+type Closure struct {
+	Function *Function
+	Upvalues []*Upvalue
+}
+
+func NewClosure(function *Function) *Closure {
+	return &Closure{
+		Function: function,
+		Upvalues: make([]*Upvalue, function.UpvalueCount),
+	}
+}
+
+func (c *Closure) String() string {
+	return c.Function.String()
+}
+
+type Class struct {
+	Name    string
+	Methods map[string]*Closure
+}
+
+func NewClass(name string) *Class {
+	return &Class{
+		Name:    name,
+		Methods: make(map[string]*Closure),
+	}
+}
+
+func (c *Class) String() string {
+	return c.Name
+}
+
+type Instance struct {
+	Class  *Class
+	Fields map[string]Value
+	// At is where the runtime error an Error instance stands for happened,
+	// kept so that rethrowing it reports the same position.
+	At token.Token
+}
+
+func NewInstance(class *Class) *Instance {
+	return &Instance{
+		Class:  class,
+		Fields: make(map[string]Value),
+	}
+}
+
+func (i *Instance) String() string {
+	return i.Class.Name + " instance"
+}
+
+// BoundMethod is a method looked up on an instance, remembering the
+// instance so it becomes "this" when the method is called.
+type BoundMethod struct {
+	Receiver Value
+	Method   *Closure
+}
+
+func (b *BoundMethod) String() string {
+	return b.Method.String()
+}
+
+// List is a list value. Like instances it is shared by reference.
+type List struct {
+	Elements []Value
+}
+
+func (l *List) String() string {
+	return l.format(nil)
+}
+
+// format prints the list inside the lists and maps in enclosing. A list
+// that contains itself, directly or not, is printed as [...] the second
+// time it is reached.
+func (l *List) format(enclosing []interface{}) string {
+	if contains(enclosing, l) {
+		return "[...]"
+	}
+	enclosing = append(enclosing, l)
+	elements := make([]string, len(l.Elements))
+	for idx, element := range l.Elements {
+		elements[idx] = element.format(enclosing)
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+func contains(containers []interface{}, container interface{}) bool {
+	for _, c := range containers {
+		if c == container {
+			return true
+		}
+	}
+	return false
+}
+
+// Map is a map value keyed by strings or numbers, iterated in insertion
+// order.
+type Map struct {
+	Keys    []Value
+	Entries map[Value]Value
+}
+
+func NewMap() *Map {
+	return &Map{
+		Keys:    []Value{},
+		Entries: make(map[Value]Value),
+	}
+}
+
+func (m *Map) Put(key, value Value) {
+	if _, ok := m.Entries[key]; !ok {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Entries[key] = value
+}
+
+func (m *Map) Delete(key Value) bool {
+	if _, ok := m.Entries[key]; !ok {
+		return false
+	}
+	delete(m.Entries, key)
+	for idx, k := range m.Keys {
+		if k == key {
+			m.Keys = append(m.Keys[:idx], m.Keys[idx+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (m *Map) String() string {
+	return m.format(nil)
+}
+
+// format is List.format for maps, which print as {...} when reached again.
+func (m *Map) format(enclosing []interface{}) string {
+	if contains(enclosing, m) {
+		return "{...}"
+	}
+	enclosing = append(enclosing, m)
+	entries := make([]string, len(m.Keys))
+	for idx, key := range m.Keys {
+		entries[idx] = key.String() + ": " + m.Entries[key].format(enclosing)
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// NativeFn is the Go side of a native function. A returned error becomes a
+// runtime error reported at the call site.
+type NativeFn func(arguments []Value) (Value, error)
+
+// Native is a Go function exposed to scripts running on the VM.
+type Native struct {
+	Name     string
+	Arity    int
+	Function NativeFn
+}
+
+func (n *Native) String() string {
+	return "<native fn>"
+}
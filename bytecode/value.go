@@ -0,0 +1,186 @@
+package bytecode
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type ValueType byte
+
+const (
+	VAL_NIL ValueType = iota
+	VAL_BOOL
+	VAL_NUMBER
+	VAL_OBJ
+)
+
+// Value is the VM's unboxed value representation. Booleans and numbers live
+// in Number so the hot arithmetic paths never allocate; strings and heap
+// objects live in Obj.
+type Value struct {
+	Type   ValueType
+	Number float64
+	Obj    interface{}
+}
+
+var NilValue = Value{Type: VAL_NIL}
+
+func BoolValue(b bool) Value {
+	if b {
+		return Value{Type: VAL_BOOL, Number: 1}
+	}
+	return Value{Type: VAL_BOOL, Number: 0}
+}
+
+func NumberValue(n float64) Value {
+	return Value{Type: VAL_NUMBER, Number: n}
+}
+
+func ObjValue(obj interface{}) Value {
+	return Value{Type: VAL_OBJ, Obj: obj}
+}
+
+func (v Value) IsNil() bool {
+	return v.Type == VAL_NIL
+}
+
+func (v Value) IsBool() bool {
+	return v.Type == VAL_BOOL
+}
+
+func (v Value) IsNumber() bool {
+	return v.Type == VAL_NUMBER
+}
+
+func (v Value) AsBool() bool {
+	return v.Number != 0
+}
+
+func (v Value) IsString() bool {
+	_, ok := v.Obj.(string)
+	return v.Type == VAL_OBJ && ok
+}
+
+func (v Value) AsString() string {
+	return v.Obj.(string)
+}
+
+// IsFalsey matches the interpreter: only nil and false are falsey.
+func (v Value) IsFalsey() bool {
+	return v.IsNil() || (v.IsBool() && !v.AsBool())
+}
+
+// ValuesEqual mirrors Interpreter.isEqual so both backends agree on ==.
+func ValuesEqual(a, b Value) bool {
+	return valuesEqual(a, b, nil)
+}
+
+// comparison is a pair of lists or maps being compared.
+type comparison struct {
+	a, b interface{}
+}
+
+// valuesEqual compares a and b inside the comparisons in progress. A pair
+// reached again while it is being compared is taken to be equal, so lists
+// and maps that contain themselves compare without recursing forever.
+func valuesEqual(a, b Value, comparing []comparison) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case VAL_NIL:
+		return true
+	case VAL_BOOL, VAL_NUMBER:
+		return a.Number == b.Number
+	case VAL_OBJ:
+		if x, ok := a.Obj.(*List); ok {
+			y, ok := b.Obj.(*List)
+			return ok && listsEqual(x, y, comparing)
+		}
+		if x, ok := a.Obj.(*Map); ok {
+			y, ok := b.Obj.(*Map)
+			return ok && mapsEqual(x, y, comparing)
+		}
+		switch a.Obj.(type) {
+		case *Closure, *Function, *Native, *Class, *Instance:
+			// Functions, classes and instances are only equal to themselves
+			return a.Obj == b.Obj
+		}
+		x, xok := a.Obj.(string)
+		y, yok := b.Obj.(string)
+		return xok && yok && x == y
+	}
+	return false
+}
+
+func listsEqual(x, y *List, comparing []comparison) bool {
+	if x == y || isComparing(comparing, x, y) {
+		return true
+	}
+	if len(x.Elements) != len(y.Elements) {
+		return false
+	}
+	comparing = append(comparing, comparison{x, y})
+	for idx := range x.Elements {
+		if !valuesEqual(x.Elements[idx], y.Elements[idx], comparing) {
+			return false
+		}
+	}
+	return true
+}
+
+func mapsEqual(x, y *Map, comparing []comparison) bool {
+	if x == y || isComparing(comparing, x, y) {
+		return true
+	}
+	if len(x.Keys) != len(y.Keys) {
+		return false
+	}
+	comparing = append(comparing, comparison{x, y})
+	for key, value := range x.Entries {
+		other, ok := y.Entries[key]
+		if !ok || !valuesEqual(value, other, comparing) {
+			return false
+		}
+	}
+	return true
+}
+
+func isComparing(comparing []comparison, a, b interface{}) bool {
+	for _, c := range comparing {
+		if c.a == a && c.b == b {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats a value exactly the way the interpreter's stringify does.
+func (v Value) String() string {
+	switch v.Type {
+	case VAL_NIL:
+		return "nil"
+	case VAL_BOOL:
+		if v.AsBool() {
+			return "true"
+		}
+		return "false"
+	case VAL_NUMBER:
+		return strconv.FormatFloat(v.Number, 'f', -1, 64)
+	}
+	if s, ok := v.Obj.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", v.Obj)
+}
+
+// format stringifies a value held in the lists and maps in enclosing.
+func (v Value) format(enclosing []interface{}) string {
+	switch obj := v.Obj.(type) {
+	case *List:
+		return obj.format(enclosing)
+	case *Map:
+		return obj.format(enclosing)
+	}
+	return v.String()
+}
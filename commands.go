@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	interpreter "github.com/shubhdevelop/YAPL/Interpreter"
+	"github.com/shubhdevelop/YAPL/Scanner"
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/bytecode"
+	"github.com/shubhdevelop/YAPL/printer"
+)
+
+// replCommand is a REPL meta-command, typed as ':name argument'.
+type replCommand struct {
+	name  string
+	usage string
+	help  string
+	run   func(s *session, argument string)
+}
+
+// replCommands is in the order :help lists them. It is filled in by init
+// because :help refers to it.
+var replCommands []replCommand
+
+func init() {
+	replCommands = []replCommand{
+		{"help", ":help", "list these commands", (*session).helpCommand},
+		{"load", ":load <file>", "run a file in this session", (*session).loadCommand},
+		{"env", ":env", "show the global variables defined so far", (*session).envCommand},
+		{"ast", ":ast <code>", "print the syntax tree of code", (*session).astCommand},
+		{"tokens", ":tokens <code>", "print the tokens the scanner produces", (*session).tokensCommand},
+		{"reset", ":reset", "forget everything defined so far", (*session).resetCommand},
+		{"time", ":time <code>", "run code and show how long it took", (*session).timeCommand},
+	}
+}
+
+// command runs line, which starts with ':'.
+func (s *session) command(line string) {
+	name, argument, _ := strings.Cut(strings.TrimPrefix(line, ":"), " ")
+	argument = strings.TrimSpace(argument)
+	for _, command := range replCommands {
+		if command.name != name {
+			continue
+		}
+		if strings.Contains(command.usage, "<") && argument == "" {
+			fmt.Println("Usage:", command.usage)
+			return
+		}
+		command.run(s, argument)
+		return
+	}
+	fmt.Printf("Unknown command ':%s'. Type :help for a list.\n", name)
+}
+
+func (s *session) helpCommand(string) {
+	for _, command := range replCommands {
+		fmt.Printf("  %-16s %s\n", command.usage, command.help)
+	}
+}
+
+func (s *session) loadCommand(path string) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Println("Can't load file:", err)
+		return
+	}
+	s.eval(string(bytes), path)
+}
+
+// envCommand lists the session's globals in name order, leaving out the native
+// functions every session starts with.
+func (s *session) envCommand(string) {
+	bindings := map[string]string{}
+	if s.machine != nil {
+		for name, value := range s.machine.Globals() {
+			if _, native := value.Obj.(*bytecode.Native); !native {
+				bindings[name] = value.String()
+			}
+		}
+	} else {
+		for name, value := range s.interpreter.Globals.Values {
+			if _, native := value.(*interpreter.NativeFunction); !native {
+				bindings[name] = interpreter.Stringify(value)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(bindings))
+	for name := range bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s = %s\n", name, bindings[name])
+	}
+}
+
+func (s *session) astCommand(source string) {
+	reporter := &yaplErrors.Reporter{Output: os.Stderr, Files: s.files}
+	statements, err := parse(source, s.files.Add("", source), reporter, true)
+	if err != nil {
+		return
+	}
+	astPrinter := &printer.AstPrinter{}
+	fmt.Println(astPrinter.PrintProgram(statements))
+}
+
+func (s *session) tokensCommand(source string) {
+	reporter := &yaplErrors.Reporter{Output: os.Stderr, Files: s.files}
+	scanner := scanner.Scanner{
+		Source:   source,
+		File:     s.files.Add("", source),
+		Reporter: reporter,
+	}
+	tokens, _ := scanner.ScanTokens()
+	for _, tok := range tokens {
+		fmt.Printf("%d:%d\t%s\n", tok.Line, tok.Column, tokenString(tok))
+	}
+}
+
+// tokenString is like token.Token.String but leaves out a missing literal.
+func tokenString(tok token.Token) string {
+	if tok.Literal == nil {
+		return fmt.Sprintf("%v %s", tok.Type, tok.Lexeme)
+	}
+	return tok.String()
+}
+
+func (s *session) resetCommand(string) {
+	s.reset()
+	fmt.Println("Session reset.")
+}
+
+// timeCommand runs source like any other input and then reports how long it took
+// to run, not counting scanning, parsing and compiling.
+func (s *session) timeCommand(source string) {
+	run, ok := s.compile(source, "")
+	if !ok {
+		return
+	}
+	start := time.Now()
+	run()
+	fmt.Printf("Took %v.\n", time.Since(start))
+}
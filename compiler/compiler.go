@@ -0,0 +1,871 @@
+package compiler
+
+import (
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/ast"
+	"github.com/shubhdevelop/YAPL/bytecode"
+)
+
+type functionType int
+
+const (
+	functionTypeScript functionType = iota
+	functionTypeFunction
+	functionTypeMethod
+	functionTypeInitializer
+)
+
+const maxLocals = 256
+
+type local struct {
+	name       string
+	depth      int
+	isCaptured bool
+}
+
+type upvalue struct {
+	index   byte
+	isLocal bool
+}
+
+// loop tracks the innermost enclosing loop so break and continue know where
+// to jump and how many locals to discard on the way out.
+type loop struct {
+	start      int
+	scopeDepth int
+	tryDepth   int
+	breaks     []int
+}
+
+// tryBlock tracks an enclosing try statement so break, continue and return
+// can pop its handlers and run its finally block before leaving it.
+type tryBlock struct {
+	finally    []ast.Stmt
+	localCount int
+	handlers   int
+}
+
+// Compiler turns a resolved AST into bytecode for the vm package. One
+// Compiler exists per function being compiled; nested function declarations
+// get a child Compiler whose enclosing field points back at the parent so
+// captured variables can be turned into upvalues.
+type Compiler struct {
+	enclosing  *Compiler
+	function   *bytecode.Function
+	kind       functionType
+	locals     []local
+	upvalues   []upvalue
+	scopeDepth int
+	loops      []*loop
+	tries      []*tryBlock
+	at         token.Token
+	reporter   *yaplErrors.Reporter
+	// identifiers maps each name already in the chunk's constant table to
+	// its index, so a name used many times takes one constant.
+	identifiers map[string]int
+}
+
+// compileError unwinds the compilers once an error has been reported. The
+// program won't run, so there is nothing to gain from compiling the rest,
+// and an error like a full constant table would be reported again for
+// every later name.
+type compileError struct{}
+
+var _ ast.ExprVisitor = (*Compiler)(nil)
+var _ ast.StmtVisitor = (*Compiler)(nil)
+
+func NewCompiler(reporter *yaplErrors.Reporter) *Compiler {
+	c := newCompiler(nil, functionTypeScript, "")
+	c.reporter = reporter
+	return c
+}
+
+func newCompiler(enclosing *Compiler, kind functionType, name string) *Compiler {
+	c := &Compiler{
+		enclosing:   enclosing,
+		function:    &bytecode.Function{Name: name},
+		kind:        kind,
+		locals:      []local{},
+		upvalues:    []upvalue{},
+		identifiers: map[string]int{},
+	}
+	if enclosing != nil {
+		c.at = enclosing.at
+		c.reporter = enclosing.reporter
+	}
+
+	// Slot zero holds the function being called, or the receiver in methods.
+	slotZero := ""
+	if kind == functionTypeMethod || kind == functionTypeInitializer {
+		slotZero = "this"
+	}
+	c.locals = append(c.locals, local{name: slotZero, depth: 0})
+	return c
+}
+
+// Compile compiles a whole program into the implicit top-level function.
+// Errors are reported through the compiler's yaplErrors.Reporter, which
+// gets at most one; the function returned is nil if there was one.
+func (c *Compiler) Compile(statements []ast.Stmt) (function *bytecode.Function) {
+	defer recoverError(&function)
+	for _, stmt := range statements {
+		c.compileStmt(stmt)
+	}
+	return c.endCompiler()
+}
+
+// CompileREPL compiles one line of REPL input like Compile, except that the
+// value of a trailing expression statement is returned from the script
+// instead of discarded, so the REPL can echo it.
+func (c *Compiler) CompileREPL(statements []ast.Stmt) (function *bytecode.Function) {
+	defer recoverError(&function)
+	if len(statements) == 0 {
+		return c.Compile(statements)
+	}
+	last, ok := statements[len(statements)-1].(ast.ExpressionStmt)
+	if !ok {
+		return c.Compile(statements)
+	}
+	for _, stmt := range statements[:len(statements)-1] {
+		c.compileStmt(stmt)
+	}
+	c.compileExpr(last.Expression)
+	c.emitOp(bytecode.OP_RETURN)
+	return c.function
+}
+
+func (c *Compiler) compileStmt(stmt ast.Stmt) {
+	stmt.Accept(c)
+}
+
+func (c *Compiler) compileExpr(expr ast.Expr) {
+	expr.Accept(c)
+}
+
+func (c *Compiler) error(message string) {
+	c.reporter.ErrorAt(c.at, message)
+	panic(compileError{})
+}
+
+// recoverError stops the unwinding started by error, leaving function nil.
+func recoverError(function **bytecode.Function) {
+	if r := recover(); r != nil {
+		if _, ok := r.(compileError); !ok {
+			panic(r)
+		}
+		*function = nil
+	}
+}
+
+// setLine records tok as the source position of the code emitted next.
+func (c *Compiler) setLine(tok token.Token) {
+	c.at = tok
+}
+
+func (c *Compiler) chunk() *bytecode.Chunk {
+	return &c.function.Chunk
+}
+
+func (c *Compiler) endCompiler() *bytecode.Function {
+	c.emitReturn()
+	return c.function
+}
+
+// Emitters
+
+func (c *Compiler) emitByte(b byte) {
+	c.chunk().Write(b, c.at)
+}
+
+func (c *Compiler) emitOp(op bytecode.OpCode) {
+	c.chunk().WriteOp(op, c.at)
+}
+
+func (c *Compiler) emitShort(value int) {
+	c.emitByte(byte((value >> 8) & 0xff))
+	c.emitByte(byte(value & 0xff))
+}
+
+func (c *Compiler) emitReturn() {
+	c.emitImplicitReturnValue()
+	c.emitOp(bytecode.OP_RETURN)
+}
+
+// emitImplicitReturnValue pushes what a bare return produces: the receiver
+// in an initializer and nil everywhere else.
+func (c *Compiler) emitImplicitReturnValue() {
+	if c.kind == functionTypeInitializer {
+		c.emitOp(bytecode.OP_GET_LOCAL)
+		c.emitByte(0)
+	} else {
+		c.emitOp(bytecode.OP_NIL)
+	}
+}
+
+func (c *Compiler) makeConstant(value bytecode.Value) int {
+	constant := c.chunk().AddConstant(value)
+	if constant > 0xffff {
+		c.error("Too many constants in one chunk.")
+	}
+	return constant
+}
+
+func (c *Compiler) emitConstant(value bytecode.Value) {
+	c.emitOp(bytecode.OP_CONSTANT)
+	c.emitShort(c.makeConstant(value))
+}
+
+func (c *Compiler) identifierConstant(name string) int {
+	if constant, ok := c.identifiers[name]; ok {
+		return constant
+	}
+	constant := c.makeConstant(bytecode.ObjValue(name))
+	c.identifiers[name] = constant
+	return constant
+}
+
+func (c *Compiler) emitJump(op bytecode.OpCode) int {
+	c.emitOp(op)
+	c.emitByte(0xff)
+	c.emitByte(0xff)
+	return len(c.chunk().Code) - 2
+}
+
+func (c *Compiler) patchJump(offset int) {
+	// -2 to adjust for the bytes of the jump offset itself.
+	jump := len(c.chunk().Code) - offset - 2
+	if jump > 0xffff {
+		c.error("Too much code to jump over.")
+	}
+	c.chunk().Code[offset] = byte((jump >> 8) & 0xff)
+	c.chunk().Code[offset+1] = byte(jump & 0xff)
+}
+
+func (c *Compiler) emitLoop(loopStart int) {
+	c.emitOp(bytecode.OP_LOOP)
+	offset := len(c.chunk().Code) - loopStart + 2
+	if offset > 0xffff {
+		c.error("Loop body too large.")
+	}
+	c.emitShort(offset)
+}
+
+// Scopes and variables
+
+func (c *Compiler) beginScope() {
+	c.scopeDepth++
+}
+
+func (c *Compiler) endScope() {
+	c.scopeDepth--
+	for len(c.locals) > 0 && c.locals[len(c.locals)-1].depth > c.scopeDepth {
+		if c.locals[len(c.locals)-1].isCaptured {
+			c.emitOp(bytecode.OP_CLOSE_UPVALUE)
+		} else {
+			c.emitOp(bytecode.OP_POP)
+		}
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+}
+
+// discardLocals pops every local deeper than depth without forgetting them,
+// for break and continue which leave scopes that are still being compiled.
+func (c *Compiler) discardLocals(depth int) {
+	for idx := len(c.locals) - 1; idx >= 0 && c.locals[idx].depth > depth; idx-- {
+		if c.locals[idx].isCaptured {
+			c.emitOp(bytecode.OP_CLOSE_UPVALUE)
+		} else {
+			c.emitOp(bytecode.OP_POP)
+		}
+	}
+}
+
+// exitTries pops the handlers and runs the finally blocks of every try
+// statement from the innermost one out to depth, for code that jumps out of
+// them.
+func (c *Compiler) exitTries(depth int) {
+	for idx := len(c.tries) - 1; idx >= depth; idx-- {
+		current := c.tries[idx]
+		for handler := 0; handler < current.handlers; handler++ {
+			c.emitOp(bytecode.OP_END_TRY)
+		}
+		if current.finally != nil {
+			c.inlineFinally(idx)
+		}
+	}
+}
+
+// inlineFinally compiles a copy of the finally block of c.tries[idx] at the
+// current position. Locals declared inside the try are still on the stack
+// but are hidden from the finally block, which can only see what was in
+// scope at the try statement itself.
+func (c *Compiler) inlineFinally(idx int) {
+	current := c.tries[idx]
+	enclosing := c.tries
+	c.tries = c.tries[:idx]
+
+	hidden := make([]string, len(c.locals)-current.localCount)
+	for offset := range hidden {
+		hidden[offset] = c.locals[current.localCount+offset].name
+		c.locals[current.localCount+offset].name = ""
+	}
+	c.compileBlock(current.finally)
+	for offset, name := range hidden {
+		c.locals[current.localCount+offset].name = name
+	}
+	c.tries = enclosing
+}
+
+// withStackValue compiles emit while the value on top of the stack is
+// counted as an unnamed local, so locals declared by emit get the right
+// slots. The value is left on the stack afterwards.
+func (c *Compiler) withStackValue(emit func()) {
+	c.beginScope()
+	c.addLocal("")
+	emit()
+	c.scopeDepth--
+	c.locals = c.locals[:len(c.locals)-1]
+}
+
+func (c *Compiler) addLocal(name string) {
+	if len(c.locals) == maxLocals {
+		c.error("Too many local variables in function.")
+	}
+	c.locals = append(c.locals, local{name: name, depth: c.scopeDepth})
+}
+
+// defineVariable binds the value on top of the stack to name, either as a
+// new local occupying that stack slot or as a global.
+func (c *Compiler) defineVariable(name string) {
+	if c.scopeDepth > 0 {
+		c.addLocal(name)
+		return
+	}
+	c.emitOp(bytecode.OP_DEFINE_GLOBAL)
+	c.emitShort(c.identifierConstant(name))
+}
+
+func (c *Compiler) resolveLocal(name string) int {
+	for idx := len(c.locals) - 1; idx >= 0; idx-- {
+		if c.locals[idx].name == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+func (c *Compiler) addUpvalue(index byte, isLocal bool) int {
+	for idx, upvalue := range c.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return idx
+		}
+	}
+	if len(c.upvalues) == maxLocals {
+		c.error("Too many closure variables in function.")
+	}
+	c.upvalues = append(c.upvalues, upvalue{index: index, isLocal: isLocal})
+	c.function.UpvalueCount = len(c.upvalues)
+	return len(c.upvalues) - 1
+}
+
+func (c *Compiler) resolveUpvalue(name string) int {
+	if c.enclosing == nil {
+		return -1
+	}
+	if local := c.enclosing.resolveLocal(name); local != -1 {
+		c.enclosing.locals[local].isCaptured = true
+		return c.addUpvalue(byte(local), true)
+	}
+	if upvalue := c.enclosing.resolveUpvalue(name); upvalue != -1 {
+		return c.addUpvalue(byte(upvalue), false)
+	}
+	return -1
+}
+
+func (c *Compiler) namedVariable(name string, assign bool) {
+	var getOp, setOp bytecode.OpCode
+	arg := c.resolveLocal(name)
+	if arg != -1 {
+		getOp, setOp = bytecode.OP_GET_LOCAL, bytecode.OP_SET_LOCAL
+	} else if arg = c.resolveUpvalue(name); arg != -1 {
+		getOp, setOp = bytecode.OP_GET_UPVALUE, bytecode.OP_SET_UPVALUE
+	} else {
+		getOp, setOp = bytecode.OP_GET_GLOBAL, bytecode.OP_SET_GLOBAL
+		if assign {
+			c.emitOp(setOp)
+		} else {
+			c.emitOp(getOp)
+		}
+		c.emitShort(c.identifierConstant(name))
+		return
+	}
+
+	if assign {
+		c.emitOp(setOp)
+	} else {
+		c.emitOp(getOp)
+	}
+	c.emitByte(byte(arg))
+}
+
+func (c *Compiler) compileFunction(stmt ast.FunctionStmt, kind functionType) {
+	fc := newCompiler(c, kind, stmt.Name.Lexeme)
+	fc.setLine(stmt.Name)
+	fc.beginScope()
+	for _, param := range stmt.Params {
+		fc.function.Arity++
+		fc.addLocal(param.Lexeme)
+	}
+	for _, bodyStmt := range stmt.Body {
+		fc.compileStmt(bodyStmt)
+	}
+	function := fc.endCompiler()
+
+	c.emitOp(bytecode.OP_CLOSURE)
+	c.emitShort(c.makeConstant(bytecode.ObjValue(function)))
+	for _, upvalue := range fc.upvalues {
+		if upvalue.isLocal {
+			c.emitByte(1)
+		} else {
+			c.emitByte(0)
+		}
+		c.emitByte(upvalue.index)
+	}
+}
+
+func (c *Compiler) compileBlock(statements []ast.Stmt) {
+	c.beginScope()
+	for _, s := range statements {
+		c.compileStmt(s)
+	}
+	c.endScope()
+}
+
+// Statement Visitors
+
+func (c *Compiler) VisitBlockStmtStmt(stmt ast.BlockStmt) interface{} {
+	c.compileBlock(stmt.Statement)
+	return nil
+}
+
+func (c *Compiler) VisitExpressionStmtStmt(stmt ast.ExpressionStmt) interface{} {
+	c.compileExpr(stmt.Expression)
+	c.emitOp(bytecode.OP_POP)
+	return nil
+}
+
+func (c *Compiler) VisitIfStmtStmt(stmt ast.IfStmt) interface{} {
+	c.compileExpr(stmt.Condition)
+
+	thenJump := c.emitJump(bytecode.OP_JUMP_IF_FALSE)
+	c.emitOp(bytecode.OP_POP)
+	c.compileStmt(stmt.ThenBranch)
+
+	elseJump := c.emitJump(bytecode.OP_JUMP)
+	c.patchJump(thenJump)
+	c.emitOp(bytecode.OP_POP)
+	if stmt.ElseBranch != nil {
+		c.compileStmt(stmt.ElseBranch)
+	}
+	c.patchJump(elseJump)
+	return nil
+}
+
+func (c *Compiler) VisitPrintStmtStmt(stmt ast.PrintStmt) interface{} {
+	c.compileExpr(stmt.Expression)
+	c.emitOp(bytecode.OP_PRINT)
+	return nil
+}
+
+func (c *Compiler) VisitVarStmtStmt(stmt ast.VarStmt) interface{} {
+	c.setLine(stmt.Name)
+	if stmt.Initializer != nil {
+		c.compileExpr(stmt.Initializer)
+	} else {
+		c.emitOp(bytecode.OP_NIL)
+	}
+	c.defineVariable(stmt.Name.Lexeme)
+	return nil
+}
+
+func (c *Compiler) VisitWhileStmtStmt(stmt ast.WhileStmt) interface{} {
+	current := &loop{
+		start:      len(c.chunk().Code),
+		scopeDepth: c.scopeDepth,
+		tryDepth:   len(c.tries),
+	}
+	c.loops = append(c.loops, current)
+
+	// A for loop's increment comes first, so that continue can loop back
+	// to it like the end of the body does. The first time round it is
+	// jumped over.
+	if stmt.Increment != nil {
+		bodyJump := c.emitJump(bytecode.OP_JUMP)
+		current.start = len(c.chunk().Code)
+		c.compileExpr(stmt.Increment)
+		c.emitOp(bytecode.OP_POP)
+		c.patchJump(bodyJump)
+	}
+
+	c.compileExpr(stmt.Condition)
+	exitJump := c.emitJump(bytecode.OP_JUMP_IF_FALSE)
+	c.emitOp(bytecode.OP_POP)
+	c.compileStmt(stmt.Body)
+	c.emitLoop(current.start)
+
+	c.patchJump(exitJump)
+	c.emitOp(bytecode.OP_POP)
+
+	// break jumps past the condition pop, the condition is already gone
+	for _, breakJump := range current.breaks {
+		c.patchJump(breakJump)
+	}
+	c.loops = c.loops[:len(c.loops)-1]
+	return nil
+}
+
+func (c *Compiler) VisitBreakStmtStmt(stmt ast.BreakStmt) interface{} {
+	if len(c.loops) == 0 {
+		return nil
+	}
+	current := c.loops[len(c.loops)-1]
+	c.exitTries(current.tryDepth)
+	c.discardLocals(current.scopeDepth)
+	current.breaks = append(current.breaks, c.emitJump(bytecode.OP_JUMP))
+	return nil
+}
+
+func (c *Compiler) VisitContinueStmtStmt(stmt ast.ContinueStmt) interface{} {
+	if len(c.loops) == 0 {
+		return nil
+	}
+	current := c.loops[len(c.loops)-1]
+	c.exitTries(current.tryDepth)
+	c.discardLocals(current.scopeDepth)
+	c.emitLoop(current.start)
+	return nil
+}
+
+func (c *Compiler) VisitFunctionStmtStmt(stmt ast.FunctionStmt) interface{} {
+	c.setLine(stmt.Name)
+	if c.scopeDepth > 0 {
+		// Declare the local before compiling the body so it can recurse.
+		c.addLocal(stmt.Name.Lexeme)
+		c.compileFunction(stmt, functionTypeFunction)
+		return nil
+	}
+	c.compileFunction(stmt, functionTypeFunction)
+	c.defineVariable(stmt.Name.Lexeme)
+	return nil
+}
+
+func (c *Compiler) VisitReturnStmtStmt(stmt ast.ReturnStmt) interface{} {
+	c.setLine(stmt.Keyword)
+	if stmt.Value == nil || c.kind == functionTypeInitializer {
+		c.emitImplicitReturnValue()
+	} else {
+		c.compileExpr(stmt.Value)
+	}
+	if len(c.tries) > 0 {
+		// The return value is already computed; finally blocks run after.
+		c.withStackValue(func() { c.exitTries(0) })
+	}
+	c.emitOp(bytecode.OP_RETURN)
+	return nil
+}
+
+// VisitTryStmtStmt installs a handler with OP_TRY for the try block and,
+// when there is a finally block, a second outer one that runs it and
+// rethrows if the try or catch block fails. The finally block is compiled
+// once for the normal path and once for that rethrow path.
+func (c *Compiler) VisitTryStmtStmt(stmt ast.TryStmt) interface{} {
+	c.setLine(stmt.Keyword)
+	current := &tryBlock{
+		finally:    stmt.FinallyBody,
+		localCount: len(c.locals),
+	}
+	c.tries = append(c.tries, current)
+
+	rethrowJump := -1
+	if stmt.FinallyBody != nil || stmt.CatchName == nil {
+		rethrowJump = c.emitJump(bytecode.OP_TRY)
+		current.handlers++
+	}
+	catchJump := -1
+	if stmt.CatchName != nil {
+		catchJump = c.emitJump(bytecode.OP_TRY)
+		current.handlers++
+	}
+
+	c.compileBlock(stmt.Body)
+	for ; current.handlers > 0; current.handlers-- {
+		c.emitOp(bytecode.OP_END_TRY)
+	}
+	doneJump := c.emitJump(bytecode.OP_JUMP)
+
+	if catchJump != -1 {
+		// The VM pushes the thrown value before jumping here.
+		c.patchJump(catchJump)
+		if rethrowJump != -1 {
+			current.handlers = 1
+		}
+		c.beginScope()
+		c.addLocal(stmt.CatchName.Lexeme)
+		for _, s := range stmt.CatchBody {
+			c.compileStmt(s)
+		}
+		for ; current.handlers > 0; current.handlers-- {
+			c.emitOp(bytecode.OP_END_TRY)
+		}
+		c.endScope()
+	}
+	c.tries = c.tries[:len(c.tries)-1]
+
+	c.patchJump(doneJump)
+	if rethrowJump == -1 {
+		return nil
+	}
+	if stmt.FinallyBody != nil {
+		c.compileBlock(stmt.FinallyBody)
+	}
+	endJump := c.emitJump(bytecode.OP_JUMP)
+
+	c.patchJump(rethrowJump)
+	c.withStackValue(func() {
+		if stmt.FinallyBody != nil {
+			c.compileBlock(stmt.FinallyBody)
+		}
+	})
+	c.emitOp(bytecode.OP_THROW)
+	c.patchJump(endJump)
+	return nil
+}
+
+func (c *Compiler) VisitThrowStmtStmt(stmt ast.ThrowStmt) interface{} {
+	c.compileExpr(stmt.Value)
+	c.setLine(stmt.Keyword)
+	c.emitOp(bytecode.OP_THROW)
+	return nil
+}
+
+func (c *Compiler) VisitClassStmtStmt(stmt ast.ClassStmt) interface{} {
+	c.setLine(stmt.Name)
+	className := stmt.Name.Lexeme
+	nameConstant := c.identifierConstant(className)
+
+	c.emitOp(bytecode.OP_CLASS)
+	c.emitShort(nameConstant)
+	c.defineVariable(className)
+
+	if stmt.Superclass != nil {
+		c.VisitVariableExpr(*stmt.Superclass)
+		c.beginScope()
+		c.addLocal("super")
+
+		c.namedVariable(className, false)
+		c.emitOp(bytecode.OP_INHERIT)
+	}
+
+	c.namedVariable(className, false)
+	for _, method := range stmt.Methods {
+		kind := functionTypeMethod
+		if method.Name.Lexeme == "init" {
+			kind = functionTypeInitializer
+		}
+		c.compileFunction(method, kind)
+		c.emitOp(bytecode.OP_METHOD)
+		c.emitShort(c.identifierConstant(method.Name.Lexeme))
+	}
+	c.emitOp(bytecode.OP_POP)
+
+	if stmt.Superclass != nil {
+		c.endScope()
+	}
+	return nil
+}
+
+// Expression Visitors
+
+func (c *Compiler) VisitAssignExpr(expr ast.Assign) interface{} {
+	c.compileExpr(expr.Value)
+	c.setLine(expr.Name)
+	c.namedVariable(expr.Name.Lexeme, true)
+	return nil
+}
+
+func (c *Compiler) VisitBinaryExpr(expr ast.Binary) interface{} {
+	c.compileExpr(expr.Left)
+	c.compileExpr(expr.Right)
+	c.setLine(expr.Operator)
+
+	switch expr.Operator.Type {
+	case token.BANG_EQUAL:
+		c.emitOp(bytecode.OP_EQUAL)
+		c.emitOp(bytecode.OP_NOT)
+	case token.EQUAL_EQUAL:
+		c.emitOp(bytecode.OP_EQUAL)
+	case token.GREATER:
+		c.emitOp(bytecode.OP_GREATER)
+	case token.GREATER_EQUAL:
+		c.emitOp(bytecode.OP_LESS)
+		c.emitOp(bytecode.OP_NOT)
+	case token.LESS:
+		c.emitOp(bytecode.OP_LESS)
+	case token.LESS_EQUAL:
+		c.emitOp(bytecode.OP_GREATER)
+		c.emitOp(bytecode.OP_NOT)
+	case token.PLUS:
+		c.emitOp(bytecode.OP_ADD)
+	case token.MINUS:
+		c.emitOp(bytecode.OP_SUBTRACT)
+	case token.STAR:
+		c.emitOp(bytecode.OP_MULTIPLY)
+	case token.SLASH:
+		c.emitOp(bytecode.OP_DIVIDE)
+	}
+	return nil
+}
+
+func (c *Compiler) VisitCallExpr(expr ast.Call) interface{} {
+	c.compileExpr(expr.Callee)
+	for _, argument := range expr.Arguments {
+		c.compileExpr(argument)
+	}
+	c.setLine(expr.Paren)
+	c.emitOp(bytecode.OP_CALL)
+	c.emitByte(byte(len(expr.Arguments)))
+	return nil
+}
+
+func (c *Compiler) VisitGetExpr(expr ast.Get) interface{} {
+	c.compileExpr(expr.Object)
+	c.setLine(expr.Name)
+	c.emitOp(bytecode.OP_GET_PROPERTY)
+	c.emitShort(c.identifierConstant(expr.Name.Lexeme))
+	return nil
+}
+
+func (c *Compiler) VisitListExpr(expr ast.List) interface{} {
+	for _, element := range expr.Elements {
+		c.compileExpr(element)
+	}
+	c.setLine(expr.Bracket)
+	c.emitOp(bytecode.OP_BUILD_LIST)
+	c.emitShort(len(expr.Elements))
+	return nil
+}
+
+func (c *Compiler) VisitMapExpr(expr ast.Map) interface{} {
+	for idx, key := range expr.Keys {
+		c.compileExpr(key)
+		c.compileExpr(expr.Values[idx])
+	}
+	c.setLine(expr.Brace)
+	c.emitOp(bytecode.OP_BUILD_MAP)
+	c.emitShort(len(expr.Keys))
+	return nil
+}
+
+func (c *Compiler) VisitIndexExpr(expr ast.Index) interface{} {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Index)
+	c.setLine(expr.Bracket)
+	c.emitOp(bytecode.OP_GET_INDEX)
+	return nil
+}
+
+func (c *Compiler) VisitIndexSetExpr(expr ast.IndexSet) interface{} {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Index)
+	c.compileExpr(expr.Value)
+	c.setLine(expr.Bracket)
+	c.emitOp(bytecode.OP_SET_INDEX)
+	return nil
+}
+
+func (c *Compiler) VisitGroupingExpr(expr ast.Grouping) interface{} {
+	c.compileExpr(expr.Expression)
+	return nil
+}
+
+func (c *Compiler) VisitLiteralExpr(expr ast.Literal) interface{} {
+	switch v := expr.Value.(type) {
+	case nil:
+		c.emitOp(bytecode.OP_NIL)
+	case bool:
+		if v {
+			c.emitOp(bytecode.OP_TRUE)
+		} else {
+			c.emitOp(bytecode.OP_FALSE)
+		}
+	case float64:
+		c.emitConstant(bytecode.NumberValue(v))
+	case string:
+		c.emitConstant(bytecode.ObjValue(v))
+	}
+	return nil
+}
+
+func (c *Compiler) VisitLogicalExpr(expr ast.Logical) interface{} {
+	c.compileExpr(expr.Left)
+	c.setLine(expr.Operator)
+
+	if expr.Operator.Type == token.OR {
+		elseJump := c.emitJump(bytecode.OP_JUMP_IF_FALSE)
+		endJump := c.emitJump(bytecode.OP_JUMP)
+		c.patchJump(elseJump)
+		c.emitOp(bytecode.OP_POP)
+		c.compileExpr(expr.Right)
+		c.patchJump(endJump)
+		return nil
+	}
+
+	endJump := c.emitJump(bytecode.OP_JUMP_IF_FALSE)
+	c.emitOp(bytecode.OP_POP)
+	c.compileExpr(expr.Right)
+	c.patchJump(endJump)
+	return nil
+}
+
+func (c *Compiler) VisitSetExpr(expr ast.Set) interface{} {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Value)
+	c.setLine(expr.Name)
+	c.emitOp(bytecode.OP_SET_PROPERTY)
+	c.emitShort(c.identifierConstant(expr.Name.Lexeme))
+	return nil
+}
+
+func (c *Compiler) VisitSuperExpr(expr ast.Super) interface{} {
+	c.setLine(expr.Keyword)
+	c.namedVariable("this", false)
+	c.namedVariable("super", false)
+	c.emitOp(bytecode.OP_GET_SUPER)
+	c.emitShort(c.identifierConstant(expr.Method.Lexeme))
+	return nil
+}
+
+func (c *Compiler) VisitThisExpr(expr ast.This) interface{} {
+	c.setLine(expr.Keyword)
+	c.namedVariable("this", false)
+	return nil
+}
+
+func (c *Compiler) VisitUnaryExpr(expr ast.Unary) interface{} {
+	c.compileExpr(expr.Right)
+	c.setLine(expr.Operator)
+	switch expr.Operator.Type {
+	case token.BANG:
+		c.emitOp(bytecode.OP_NOT)
+	case token.MINUS:
+		c.emitOp(bytecode.OP_NEGATE)
+	}
+	return nil
+}
+
+func (c *Compiler) VisitVariableExpr(expr ast.Variable) interface{} {
+	c.setLine(expr.Name)
+	c.namedVariable(expr.Name.Lexeme, false)
+	return nil
+}
@@ -0,0 +1,38 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/frontend"
+)
+
+func compile(t *testing.T, source string) *yaplErrors.Reporter {
+	t.Helper()
+	reporter := &yaplErrors.Reporter{}
+	result := frontend.Parse(source, frontend.Options{Reporter: reporter})
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors)
+	}
+	NewCompiler(reporter).Compile(result.Statements)
+	return reporter
+}
+
+// A global's name is one constant however often it is used.
+func TestGlobalNamesShareAConstant(t *testing.T) {
+	source := "var x = 0;\n" + strings.Repeat("x = x;\n", 70000)
+	if reporter := compile(t, source); reporter.HadError() {
+		t.Fatal(reporter.Errors)
+	}
+}
+
+func TestConstantOverflowIsReportedOnce(t *testing.T) {
+	reporter := compile(t, strings.Repeat("print \"s\";\n", 70000))
+	if len(reporter.Errors) != 1 {
+		t.Fatalf("got %d errors, want 1", len(reporter.Errors))
+	}
+	if !strings.Contains(reporter.Errors[0].Error(), "Too many constants in one chunk.") {
+		t.Fatal(reporter.Errors[0])
+	}
+}
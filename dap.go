@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/shubhdevelop/YAPL/dap"
+)
+
+// runDAP implements 'Lox dap', which runs a debug adapter for an editor on
+// standard input and output, and returns the exit status.
+func runDAP(args []string) int {
+	flags := flag.NewFlagSet("dap", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: Lox dap")
+		fmt.Fprintln(flags.Output(), "Speaks the Debug Adapter Protocol on standard input and output.")
+	}
+	flags.Parse(args)
+
+	if err := dap.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
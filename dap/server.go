@@ -0,0 +1,486 @@
+// Package dap is a Debug Adapter Protocol server for YAPL, so editors such
+// as VS Code can run a script under the debugger: stop at breakpoints,
+// step in, over and out, and look at the call stack and the variables of
+// each scope in it.
+//
+// The server talks over a pair of streams, normally standard input and
+// output, so the program's own output is sent to the client as output
+// events. A session debugs one script, named by the launch request.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	interpreter "github.com/shubhdevelop/YAPL/Interpreter"
+	"github.com/shubhdevelop/YAPL/ast"
+	"github.com/shubhdevelop/YAPL/debugger"
+	"github.com/shubhdevelop/YAPL/environment"
+	"github.com/shubhdevelop/YAPL/frontend"
+)
+
+// Server is one debug session.
+type Server struct {
+	conn conn
+	// writeMu serializes messages, which the program's output and the
+	// debugger's events send from other goroutines.
+	writeMu sync.Mutex
+	seq     int
+
+	// lineBase and columnBase are what the client counts lines and
+	// columns from.
+	lineBase   int
+	columnBase int
+
+	program    string
+	statements []ast.Stmt
+	// statementLines holds the lines a statement starts on, where a
+	// breakpoint can stop.
+	statementLines map[int]bool
+	debugger       *debugger.Debugger
+	// breakpoints are the lines set for each source path, which may come
+	// before the launch request names the program.
+	breakpoints map[string][]int
+	launched    bool
+	configured  bool
+	started     bool
+	stopOnEntry bool
+
+	// mu guards what changes when the program stops or goes on.
+	mu      sync.Mutex
+	stopped bool
+	// references maps the variablesReference of each scope sent since the
+	// program stopped to its environment.
+	references []*environment.Environment
+
+	// afterResponse, if set, runs once the response to the current request
+	// has been sent, so a step's stopped event can't come before it.
+	afterResponse func()
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		conn:        conn{in: bufio.NewReader(in), out: out},
+		lineBase:    1,
+		columnBase:  1,
+		breakpoints: map[string][]int{},
+	}
+}
+
+// Serve handles requests until the client disconnects or the input ends.
+func (s *Server) Serve() error {
+	for {
+		body, err := s.conn.read()
+		if err == io.EOF {
+			s.quit()
+			return nil
+		}
+		if err != nil {
+			s.quit()
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("dap: %v", err)
+		}
+		if req.Type != "request" {
+			continue
+		}
+
+		result, err := s.handle(req)
+		response := response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: result}
+		if err != nil {
+			response.Message = err.Error()
+		}
+		if err := s.send(&response); err != nil {
+			return err
+		}
+		if s.afterResponse != nil {
+			s.afterResponse()
+			s.afterResponse = nil
+		}
+		switch req.Command {
+		case "initialize":
+			if err := s.event("initialized", nil); err != nil {
+				return err
+			}
+		case "launch", "configurationDone":
+			s.start()
+		case "disconnect":
+			return nil
+		}
+	}
+}
+
+// send writes a response or event, numbering it.
+func (s *Server) send(msg interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.seq++
+	switch msg := msg.(type) {
+	case *response:
+		msg.Seq = s.seq
+	case *event:
+		msg.Seq = s.seq
+	}
+	return s.conn.write(msg)
+}
+
+func (s *Server) event(name string, body interface{}) error {
+	return s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+// output sends text the program printed, or an error, to the client.
+func (s *Server) output(category, text string) {
+	s.event("output", map[string]string{"category": category, "output": text})
+}
+
+// outputWriter is the interpreter's Stdout.
+type outputWriter struct {
+	server *Server
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	w.server.output("stdout", string(p))
+	return len(p), nil
+}
+
+func decode(req request, arguments interface{}) error {
+	if len(req.Arguments) == 0 {
+		return nil
+	}
+	return json.Unmarshal(req.Arguments, arguments)
+}
+
+// handle runs one request and returns the body of its response.
+func (s *Server) handle(req request) (interface{}, error) {
+	switch req.Command {
+	case "initialize":
+		var arguments InitializeArguments
+		if err := decode(req, &arguments); err != nil {
+			return nil, err
+		}
+		if arguments.LinesStartAt1 != nil && !*arguments.LinesStartAt1 {
+			s.lineBase = 0
+		}
+		if arguments.ColumnsStartAt1 != nil && !*arguments.ColumnsStartAt1 {
+			s.columnBase = 0
+		}
+		return Capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsTerminateRequest:         true,
+			SupportsEvaluateForHovers:        true,
+		}, nil
+	case "launch":
+		var arguments LaunchArguments
+		if err := decode(req, &arguments); err != nil {
+			return nil, err
+		}
+		return nil, s.launch(arguments)
+	case "setBreakpoints":
+		var arguments SetBreakpointsArguments
+		if err := decode(req, &arguments); err != nil {
+			return nil, err
+		}
+		return s.setBreakpoints(arguments), nil
+	case "configurationDone":
+		s.configured = true
+		return nil, nil
+	case "threads":
+		return map[string]interface{}{"threads": []Thread{{ID: threadID, Name: "main"}}}, nil
+	case "stackTrace":
+		return s.stackTrace()
+	case "scopes":
+		var arguments ScopesArguments
+		if err := decode(req, &arguments); err != nil {
+			return nil, err
+		}
+		return s.scopes(arguments.FrameID)
+	case "variables":
+		var arguments VariablesArguments
+		if err := decode(req, &arguments); err != nil {
+			return nil, err
+		}
+		return s.variables(arguments.VariablesReference)
+	case "evaluate":
+		var arguments EvaluateArguments
+		if err := decode(req, &arguments); err != nil {
+			return nil, err
+		}
+		return s.evaluate(arguments)
+	case "continue":
+		return map[string]bool{"allThreadsContinued": true}, s.resume((*debugger.Debugger).Continue)
+	case "next":
+		return nil, s.resume((*debugger.Debugger).StepOver)
+	case "stepIn":
+		return nil, s.resume((*debugger.Debugger).StepIn)
+	case "stepOut":
+		return nil, s.resume((*debugger.Debugger).StepOut)
+	case "pause":
+		if s.debugger != nil {
+			s.debugger.Pause()
+		}
+		return nil, nil
+	case "terminate", "disconnect":
+		s.quit()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported request '%s'", req.Command)
+}
+
+// launch loads the program. It starts once the client has also sent
+// configurationDone, so breakpoints are set first.
+func (s *Server) launch(arguments LaunchArguments) error {
+	if s.launched {
+		return fmt.Errorf("a program has already been launched")
+	}
+	if arguments.Program == "" {
+		return fmt.Errorf("launch needs a 'program' to debug")
+	}
+	program, err := filepath.Abs(arguments.Program)
+	if err != nil {
+		return err
+	}
+	source, err := os.ReadFile(program)
+	if err != nil {
+		return err
+	}
+	statements, err := parse(string(source), program)
+	if err != nil {
+		return err
+	}
+
+	s.program = program
+	s.statements = statements
+	s.statementLines = map[int]bool{}
+	statementLines(statements, s.statementLines)
+	s.stopOnEntry = arguments.StopOnEntry
+
+	interpreter := interpreter.NewInterpreter()
+	interpreter.File = program
+	interpreter.Stdout = outputWriter{server: s}
+	s.debugger = debugger.New(interpreter)
+	if !arguments.NoDebug {
+		s.debugger.SetBreakpoints(s.breakpoints[program])
+	}
+	s.launched = true
+	return nil
+}
+
+// parse scans, parses and resolves source, returning its syntax errors as
+// one error.
+func parse(source, path string) ([]ast.Stmt, error) {
+	result := frontend.Parse(source, frontend.Options{})
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("%s has errors:\n%v", filepath.Base(path), errors.Join(result.Errors...))
+	}
+	return result.Statements, nil
+}
+
+// statementLines records the lines statements start on, nested ones
+// included.
+func statementLines(statements []ast.Stmt, lines map[int]bool) {
+	for _, stmt := range statements {
+		if at, ok := ast.StmtToken(stmt); ok {
+			lines[at.Line] = true
+		}
+		switch stmt := stmt.(type) {
+		case ast.BlockStmt:
+			statementLines(stmt.Statement, lines)
+		case ast.IfStmt:
+			statementLines([]ast.Stmt{stmt.ThenBranch}, lines)
+			if stmt.ElseBranch != nil {
+				statementLines([]ast.Stmt{stmt.ElseBranch}, lines)
+			}
+		case ast.WhileStmt:
+			statementLines([]ast.Stmt{stmt.Body}, lines)
+		case ast.FunctionStmt:
+			statementLines(stmt.Body, lines)
+		case ast.ClassStmt:
+			for _, method := range stmt.Methods {
+				statementLines(method.Body, lines)
+			}
+		case ast.TryStmt:
+			statementLines(stmt.Body, lines)
+			statementLines(stmt.CatchBody, lines)
+			statementLines(stmt.FinallyBody, lines)
+		}
+	}
+}
+
+// start runs the program once it has been launched and configured, and
+// passes what the debugger reports on to the client.
+func (s *Server) start() {
+	if !s.launched || !s.configured || s.started {
+		return
+	}
+	s.started = true
+	s.debugger.Start(s.statements, s.stopOnEntry)
+	go func() {
+		for stop := range s.debugger.Events() {
+			if stop.Reason == debugger.Exited {
+				exitCode := 0
+				if stop.Err != nil {
+					s.output("stderr", fmt.Sprintf("Runtime error: %v\n", stop.Err))
+					exitCode = 70
+				}
+				s.event("exited", map[string]int{"exitCode": exitCode})
+				s.event("terminated", nil)
+				return
+			}
+			s.mu.Lock()
+			s.stopped = true
+			s.references = nil
+			s.mu.Unlock()
+			s.event("stopped", map[string]interface{}{
+				"reason":            string(stop.Reason),
+				"threadId":          threadID,
+				"allThreadsStopped": true,
+			})
+		}
+	}()
+}
+
+// resume lets a stopped program go on with step, one of the Debugger's
+// methods.
+func (s *Server) resume(step func(*debugger.Debugger)) error {
+	s.mu.Lock()
+	stopped := s.stopped
+	s.stopped = false
+	s.references = nil
+	s.mu.Unlock()
+	if !stopped {
+		return fmt.Errorf("the program isn't stopped")
+	}
+	s.afterResponse = func() { step(s.debugger) }
+	return nil
+}
+
+// quit ends the program, if there is one running.
+func (s *Server) quit() {
+	if s.started {
+		s.mu.Lock()
+		s.stopped = false
+		s.mu.Unlock()
+		s.debugger.Quit()
+	}
+}
+
+func (s *Server) setBreakpoints(arguments SetBreakpointsArguments) map[string]interface{} {
+	path := arguments.Source.Path
+	if absolute, err := filepath.Abs(path); err == nil {
+		path = absolute
+	}
+	lines := []int{}
+	breakpoints := []Breakpoint{}
+	for _, requested := range arguments.Breakpoints {
+		line := requested.Line - s.lineBase + 1
+		breakpoint := Breakpoint{Verified: true, Line: requested.Line}
+		// Until the program is launched any line might hold a statement.
+		if s.launched && (path != s.program || !s.statementLines[line]) {
+			breakpoint.Verified = false
+			breakpoint.Message = "No statement starts on this line."
+		} else {
+			lines = append(lines, line)
+		}
+		breakpoints = append(breakpoints, breakpoint)
+	}
+	s.breakpoints[path] = lines
+	if s.launched && path == s.program {
+		s.debugger.SetBreakpoints(lines)
+	}
+	return map[string]interface{}{"breakpoints": breakpoints}
+}
+
+// stack returns the stopped program's call stack, innermost first.
+func (s *Server) stack() ([]interpreter.StackFrame, error) {
+	s.mu.Lock()
+	stopped := s.stopped
+	s.mu.Unlock()
+	if !stopped {
+		return nil, fmt.Errorf("the program isn't stopped")
+	}
+	return s.debugger.Stack(), nil
+}
+
+func (s *Server) stackTrace() (interface{}, error) {
+	stack, err := s.stack()
+	if err != nil {
+		return nil, err
+	}
+	frames := make([]StackFrame, len(stack))
+	for idx, frame := range stack {
+		frames[idx] = StackFrame{
+			ID:     idx,
+			Name:   frame.Function,
+			Source: Source{Name: filepath.Base(s.program), Path: s.program},
+			Line:   frame.Line - 1 + s.lineBase,
+			Column: frame.Column - 1 + s.columnBase,
+		}
+	}
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, nil
+}
+
+// frameEnvironment returns the environment of a frame of the stopped
+// program.
+func (s *Server) frameEnvironment(frameID int) (*environment.Environment, error) {
+	stack, err := s.stack()
+	if err != nil {
+		return nil, err
+	}
+	if frameID < 0 || frameID >= len(stack) {
+		return nil, fmt.Errorf("there is no frame %d", frameID)
+	}
+	return stack[frameID].Environment, nil
+}
+
+func (s *Server) scopes(frameID int) (interface{}, error) {
+	env, err := s.frameEnvironment(frameID)
+	if err != nil {
+		return nil, err
+	}
+	scopes := []Scope{}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, scope := range s.debugger.Scopes(env) {
+		s.references = append(s.references, scope.Environment)
+		scopes = append(scopes, Scope{Name: scope.Name, VariablesReference: len(s.references)})
+	}
+	return map[string]interface{}{"scopes": scopes}, nil
+}
+
+func (s *Server) variables(reference int) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.stopped || reference < 1 || reference > len(s.references) {
+		return nil, fmt.Errorf("no variables for reference %d", reference)
+	}
+	variables := []Variable{}
+	for _, variable := range debugger.Variables(s.references[reference-1]) {
+		variables = append(variables, Variable{Name: variable.Name, Value: variable.Value})
+	}
+	return map[string]interface{}{"variables": variables}, nil
+}
+
+// evaluate shows the value of a variable, the only expression it knows.
+func (s *Server) evaluate(arguments EvaluateArguments) (interface{}, error) {
+	frameID := 0
+	if arguments.FrameID != nil {
+		frameID = *arguments.FrameID
+	}
+	env, err := s.frameEnvironment(frameID)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := debugger.Lookup(env, arguments.Expression)
+	if !ok {
+		return nil, fmt.Errorf("no variable '%s' here", arguments.Expression)
+	}
+	return map[string]interface{}{"result": debugger.Describe(value), "variablesReference": 0}, nil
+}
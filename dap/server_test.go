@@ -0,0 +1,239 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// client is the editor side of a debug session with a Server running in
+// the test.
+type client struct {
+	t    *testing.T
+	conn conn
+	seq  int
+	done chan error
+	// output collects what the program printed, from output events.
+	output strings.Builder
+}
+
+// received is any message from the server.
+type received struct {
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Command    string          `json:"command"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+func newClient(t *testing.T) *client {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{
+		t:    t,
+		conn: conn{in: bufio.NewReader(clientIn), out: clientOut},
+		done: make(chan error, 1),
+	}
+	go func() {
+		err := NewServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+		c.done <- err
+	}()
+	t.Cleanup(func() { clientOut.Close() })
+	return c
+}
+
+// next returns the next message other than an output event.
+func (c *client) next() received {
+	c.t.Helper()
+	for {
+		body, err := c.conn.read()
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		var msg received
+		if err := json.Unmarshal(body, &msg); err != nil {
+			c.t.Fatal(err)
+		}
+		if msg.Type == "event" && msg.Event == "output" {
+			var output struct {
+				Output string `json:"output"`
+			}
+			if err := json.Unmarshal(msg.Body, &output); err != nil {
+				c.t.Fatal(err)
+			}
+			c.output.WriteString(output.Output)
+			continue
+		}
+		return msg
+	}
+}
+
+// call sends a request and decodes the body of its response into body,
+// which may be nil.
+func (c *client) call(command string, arguments, body interface{}) {
+	c.t.Helper()
+	c.seq++
+	raw, err := json.Marshal(arguments)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if err := c.conn.write(request{Seq: c.seq, Type: "request", Command: command, Arguments: raw}); err != nil {
+		c.t.Fatal(err)
+	}
+	response := c.next()
+	if response.Type != "response" || response.RequestSeq != c.seq || response.Command != command {
+		c.t.Fatalf("%s: got %+v, want its response", command, response)
+	}
+	if !response.Success {
+		c.t.Fatalf("%s: %s", command, response.Message)
+	}
+	if body != nil {
+		if err := json.Unmarshal(response.Body, body); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+}
+
+// expect reads the next event, which must be called name, and decodes its
+// body into body, which may be nil.
+func (c *client) expect(name string, body interface{}) {
+	c.t.Helper()
+	msg := c.next()
+	if msg.Type != "event" || msg.Event != name {
+		c.t.Fatalf("got %+v, want a %s event", msg, name)
+	}
+	if body != nil {
+		if err := json.Unmarshal(msg.Body, body); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+}
+
+const program = `var total = 0;
+fun add(n) {
+  total = total + n;
+}
+add(1);
+add(2);
+print total;
+`
+
+func TestSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "program.yapl")
+	if err := os.WriteFile(path, []byte(program), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t)
+
+	var capabilities Capabilities
+	c.call("initialize", map[string]interface{}{"adapterID": "yapl", "linesStartAt1": true}, &capabilities)
+	if !capabilities.SupportsConfigurationDoneRequest {
+		t.Fatalf("capabilities %+v", capabilities)
+	}
+	c.expect("initialized", nil)
+
+	var set struct {
+		Breakpoints []Breakpoint `json:"breakpoints"`
+	}
+	c.call("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: path}, Breakpoints: []SourceBreakpoint{{Line: 3}}}, &set)
+	if len(set.Breakpoints) != 1 || !set.Breakpoints[0].Verified || set.Breakpoints[0].Line != 3 {
+		t.Fatalf("breakpoints %+v", set.Breakpoints)
+	}
+	c.call("launch", LaunchArguments{Program: path}, nil)
+	c.call("configurationDone", nil, nil)
+
+	for _, want := range []string{"1", "2"} {
+		var stopped struct {
+			Reason   string `json:"reason"`
+			ThreadID int    `json:"threadId"`
+		}
+		c.expect("stopped", &stopped)
+		if stopped.Reason != "breakpoint" || stopped.ThreadID != threadID {
+			t.Fatalf("stopped %+v", stopped)
+		}
+
+		var trace struct {
+			StackFrames []StackFrame `json:"stackFrames"`
+		}
+		c.call("stackTrace", StackTraceArguments{ThreadID: threadID}, &trace)
+		if len(trace.StackFrames) != 2 {
+			t.Fatalf("stack %+v", trace.StackFrames)
+		}
+		if top := trace.StackFrames[0]; top.Name != "add" || top.Line != 3 || top.Source.Path != path {
+			t.Errorf("top frame %+v", top)
+		}
+
+		var evaluated struct {
+			Result string `json:"result"`
+		}
+		c.call("evaluate", EvaluateArguments{Expression: "n"}, &evaluated)
+		if evaluated.Result != want {
+			t.Errorf("n is %q, want %q", evaluated.Result, want)
+		}
+
+		c.call("continue", map[string]int{"threadId": threadID}, nil)
+	}
+
+	var exited struct {
+		ExitCode int `json:"exitCode"`
+	}
+	c.expect("exited", &exited)
+	if exited.ExitCode != 0 {
+		t.Errorf("exit code %d", exited.ExitCode)
+	}
+	c.expect("terminated", nil)
+	if c.output.String() != "3\n" {
+		t.Errorf("program printed %q", c.output.String())
+	}
+
+	c.call("disconnect", nil, nil)
+	if err := <-c.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A breakpoint set after launch is only verified on a line where a
+// statement starts.
+func TestBreakpointsAfterLaunch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "program.yapl")
+	if err := os.WriteFile(path, []byte(program), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t)
+	c.call("initialize", struct{}{}, nil)
+	c.expect("initialized", nil)
+	c.call("launch", LaunchArguments{Program: path}, nil)
+
+	var set struct {
+		Breakpoints []Breakpoint `json:"breakpoints"`
+	}
+	c.call("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: path}, Breakpoints: []SourceBreakpoint{{Line: 4}, {Line: 6}}}, &set)
+	if len(set.Breakpoints) != 2 || set.Breakpoints[0].Verified || !set.Breakpoints[1].Verified {
+		t.Fatalf("breakpoints %+v", set.Breakpoints)
+	}
+
+	c.call("configurationDone", nil, nil)
+	c.expect("stopped", nil)
+	var trace struct {
+		StackFrames []StackFrame `json:"stackFrames"`
+	}
+	c.call("stackTrace", StackTraceArguments{ThreadID: threadID}, &trace)
+	if len(trace.StackFrames) != 1 || trace.StackFrames[0].Line != 6 {
+		t.Fatalf("stopped at %+v, want line 6", trace.StackFrames)
+	}
+	c.call("continue", map[string]int{"threadId": threadID}, nil)
+	c.expect("exited", nil)
+	c.expect("terminated", nil)
+	c.call("disconnect", nil, nil)
+	if err := <-c.done; err != nil {
+		t.Fatal(err)
+	}
+}
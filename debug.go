@@ -0,0 +1,330 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	interpreter "github.com/shubhdevelop/YAPL/Interpreter"
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/ast"
+	"github.com/shubhdevelop/YAPL/debugger"
+)
+
+// debugSession is a program being run under --debug.
+type debugSession struct {
+	debugger *debugger.Debugger
+	file     string
+	lines    []string
+	// out is where the program's output and the session's go.
+	out io.Writer
+	// stopped is the event the program is stopped at.
+	stopped debugger.Event
+	// frame is the stack frame locals and print look at, 0 being the
+	// innermost.
+	frame int
+	// last is the last command typed, which Enter on its own repeats.
+	last string
+}
+
+// debugCommand is a command typed at the (ydb) prompt. run returns true if
+// it let the program go on.
+type debugCommand struct {
+	names []string
+	usage string
+	help  string
+	run   func(s *debugSession, argument string) bool
+}
+
+// debugCommands is in the order help lists them. It is filled in by init
+// because help refers to it.
+var debugCommands []debugCommand
+
+func init() {
+	debugCommands = []debugCommand{
+		{[]string{"help", "h"}, "help", "list these commands", (*debugSession).helpCommand},
+		{[]string{"break", "b"}, "break <line>", "stop before the statements on a line", (*debugSession).breakCommand},
+		{[]string{"delete", "d"}, "delete [line]", "remove the breakpoint on a line, or every breakpoint", (*debugSession).deleteCommand},
+		{[]string{"breakpoints"}, "breakpoints", "list the breakpoints", (*debugSession).breakpointsCommand},
+		{[]string{"continue", "c"}, "continue", "run until a breakpoint or the end", (*debugSession).continueCommand},
+		{[]string{"step", "s"}, "step", "run to the next statement, going into calls", (*debugSession).stepCommand},
+		{[]string{"next", "n"}, "next", "run to the next statement, going over calls", (*debugSession).nextCommand},
+		{[]string{"out", "o"}, "out", "run until the current function returns", (*debugSession).outCommand},
+		{[]string{"stack", "bt"}, "stack", "show the calls in progress, innermost first", (*debugSession).stackCommand},
+		{[]string{"frame", "f"}, "frame <n>", "look at the variables of call n in the stack", (*debugSession).frameCommand},
+		{[]string{"locals", "env"}, "locals", "show the environment chain: locals, enclosing scopes and globals", (*debugSession).localsCommand},
+		{[]string{"print", "p"}, "print <name>", "show the value of a variable", (*debugSession).printCommand},
+		{[]string{"list", "l"}, "list", "show the source around the current line", (*debugSession).listCommand},
+		{[]string{"quit", "q"}, "quit", "stop the program and leave", (*debugSession).quitCommand},
+	}
+}
+
+// runDebugger runs statements from source under an interactive debugger
+// that stops before the first one. files holds source, to quote a runtime
+// error from. It returns the program's runtime error, if it had one.
+func runDebugger(statements []ast.Stmt, source, file string, files *token.FileSet) error {
+	s := newDebugSession(source, file, os.Stdout)
+	editor := newLineEditor(s.completions)
+	// Debugger commands don't belong in the REPL's history.
+	editor.history, editor.historyFile = nil, ""
+	return s.run(statements, editor, files)
+}
+
+func newDebugSession(source, file string, out io.Writer) *debugSession {
+	interpreter := interpreter.NewInterpreter()
+	interpreter.File = file
+	interpreter.Stdout = out
+	return &debugSession{
+		debugger: debugger.New(interpreter),
+		file:     file,
+		lines:    strings.Split(source, "\n"),
+		out:      out,
+	}
+}
+
+// run starts the program and reads commands from editor each time it
+// stops.
+func (s *debugSession) run(statements []ast.Stmt, editor *lineEditor, files *token.FileSet) error {
+	fmt.Fprintln(s.out, "Debugging", s.file+". Type help for a list of commands.")
+	s.debugger.Start(statements, true)
+	for event := range s.debugger.Events() {
+		if event.Reason == debugger.Exited {
+			if event.Err != nil {
+				fmt.Fprintln(s.out, "Runtime error:", yaplErrors.FormatError(event.Err, files))
+				return event.Err
+			}
+			fmt.Fprintln(s.out, "Program exited.")
+			return nil
+		}
+		s.stopped, s.frame = event, 0
+		fmt.Fprintf(s.out, "Stopped at %s:%d (%s)\n", s.file, event.At.Line, event.Reason)
+		s.showLine(event.At.Line, true)
+		s.prompt(editor)
+	}
+	return nil
+}
+
+// prompt reads commands until one lets the program go on. Enter on its
+// own repeats the last command, which makes stepping quicker.
+func (s *debugSession) prompt(editor *lineEditor) {
+	for {
+		line, err := editor.readLine("(ydb) ")
+		if errors.Is(err, errInterrupted) {
+			continue
+		}
+		if err == io.EOF {
+			s.debugger.Quit()
+			return
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = s.last
+		}
+		if line == "" {
+			continue
+		}
+		s.last = line
+		if s.command(line) {
+			return
+		}
+	}
+}
+
+// command runs line and reports whether it let the program go on.
+func (s *debugSession) command(line string) bool {
+	name, argument, _ := strings.Cut(line, " ")
+	argument = strings.TrimSpace(argument)
+	for _, command := range debugCommands {
+		for _, alias := range command.names {
+			if alias != name {
+				continue
+			}
+			if strings.Contains(command.usage, "<") && argument == "" {
+				fmt.Fprintln(s.out, "Usage:", command.usage)
+				return false
+			}
+			return command.run(s, argument)
+		}
+	}
+	fmt.Fprintf(s.out, "Unknown command '%s'. Type help for a list.\n", name)
+	return false
+}
+
+// completions returns the commands that start with word.
+func (s *debugSession) completions(word string) []string {
+	var names []string
+	for _, command := range debugCommands {
+		if strings.HasPrefix(command.names[0], word) {
+			names = append(names, command.names[0])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// showLine prints a line of the source with its number, marked with an
+// arrow if it is where the program is stopped.
+func (s *debugSession) showLine(line int, current bool) {
+	if line < 1 || line > len(s.lines) {
+		return
+	}
+	marker := "  "
+	if current {
+		marker = "->"
+	}
+	fmt.Fprintf(s.out, "%s %4d  %s\n", marker, line, strings.TrimRight(s.lines[line-1], "\r"))
+}
+
+func (s *debugSession) helpCommand(string) bool {
+	for _, command := range debugCommands {
+		names := command.usage
+		if len(command.names) > 1 {
+			names += " (" + strings.Join(command.names[1:], ", ") + ")"
+		}
+		fmt.Fprintf(s.out, "  %-24s %s\n", names, command.help)
+	}
+	fmt.Fprintln(s.out, "  Enter on its own repeats the last command.")
+	return false
+}
+
+// lineArgument parses a line number typed as an argument.
+func (s *debugSession) lineArgument(argument string) (int, bool) {
+	line, err := strconv.Atoi(argument)
+	if err != nil || line < 1 || line > len(s.lines) {
+		fmt.Fprintf(s.out, "'%s' is not a line of %s.\n", argument, s.file)
+		return 0, false
+	}
+	return line, true
+}
+
+func (s *debugSession) breakCommand(argument string) bool {
+	line, ok := s.lineArgument(argument)
+	if !ok {
+		return false
+	}
+	s.debugger.SetBreakpoints(append(s.debugger.Breakpoints(), line))
+	fmt.Fprintf(s.out, "Breakpoint at %s:%d\n", s.file, line)
+	return false
+}
+
+func (s *debugSession) deleteCommand(argument string) bool {
+	if argument == "" {
+		s.debugger.SetBreakpoints(nil)
+		fmt.Fprintln(s.out, "Deleted every breakpoint.")
+		return false
+	}
+	line, ok := s.lineArgument(argument)
+	if !ok {
+		return false
+	}
+	var kept []int
+	for _, breakpoint := range s.debugger.Breakpoints() {
+		if breakpoint != line {
+			kept = append(kept, breakpoint)
+		}
+	}
+	s.debugger.SetBreakpoints(kept)
+	return false
+}
+
+func (s *debugSession) breakpointsCommand(string) bool {
+	breakpoints := s.debugger.Breakpoints()
+	if len(breakpoints) == 0 {
+		fmt.Fprintln(s.out, "No breakpoints.")
+	}
+	for _, line := range breakpoints {
+		s.showLine(line, line == s.stopped.At.Line)
+	}
+	return false
+}
+
+func (s *debugSession) continueCommand(string) bool {
+	s.debugger.Continue()
+	return true
+}
+
+func (s *debugSession) stepCommand(string) bool {
+	s.debugger.StepIn()
+	return true
+}
+
+func (s *debugSession) nextCommand(string) bool {
+	s.debugger.StepOver()
+	return true
+}
+
+func (s *debugSession) outCommand(string) bool {
+	s.debugger.StepOut()
+	return true
+}
+
+func (s *debugSession) stackCommand(string) bool {
+	for idx, frame := range s.debugger.Stack() {
+		marker := " "
+		if idx == s.frame {
+			marker = "*"
+		}
+		fmt.Fprintf(s.out, "%s #%d  %s at %s:%d\n", marker, idx, frame.Function, s.file, frame.Line)
+	}
+	return false
+}
+
+func (s *debugSession) frameCommand(argument string) bool {
+	stack := s.debugger.Stack()
+	frame, err := strconv.Atoi(argument)
+	if err != nil || frame < 0 || frame >= len(stack) {
+		fmt.Fprintf(s.out, "There is no frame '%s'; the stack has %d.\n", argument, len(stack))
+		return false
+	}
+	s.frame = frame
+	fmt.Fprintf(s.out, "#%d  %s at %s:%d\n", frame, stack[frame].Function, s.file, stack[frame].Line)
+	s.showLine(stack[frame].Line, frame == 0)
+	return false
+}
+
+func (s *debugSession) localsCommand(string) bool {
+	frame := s.debugger.Stack()[s.frame]
+	for _, scope := range s.debugger.Scopes(frame.Environment) {
+		fmt.Fprintf(s.out, "%s:\n", scope.Name)
+		variables := debugger.Variables(scope.Environment)
+		if len(variables) == 0 {
+			fmt.Fprintln(s.out, "  (none)")
+		}
+		for _, variable := range variables {
+			fmt.Fprintf(s.out, "  %s = %s\n", variable.Name, variable.Value)
+		}
+	}
+	return false
+}
+
+func (s *debugSession) printCommand(name string) bool {
+	frame := s.debugger.Stack()[s.frame]
+	value, ok := debugger.Lookup(frame.Environment, name)
+	if !ok {
+		fmt.Fprintf(s.out, "No variable '%s' here.\n", name)
+		return false
+	}
+	fmt.Fprintf(s.out, "%s = %s\n", name, debugger.Describe(value))
+	return false
+}
+
+// listContext is how many lines list shows on each side of the current one.
+const listContext = 5
+
+func (s *debugSession) listCommand(string) bool {
+	current := s.debugger.Stack()[s.frame].Line
+	for line := current - listContext; line <= current+listContext; line++ {
+		s.showLine(line, line == current)
+	}
+	return false
+}
+
+func (s *debugSession) quitCommand(string) bool {
+	s.debugger.Quit()
+	return true
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+)
+
+// debugTranscript runs source under --debug with commands typed at the prompt and
+// returns everything written.
+func debugTranscript(t *testing.T, source, commands string) string {
+	t.Helper()
+	files := &token.FileSet{}
+	reporter := &yaplErrors.Reporter{Files: files}
+	statements, err := parse(source, files.Add("test.yapl", source), reporter, false)
+	if err != nil {
+		t.Fatal(reporter.Errors)
+	}
+	var out bytes.Buffer
+	s := newDebugSession(source, "test.yapl", &out)
+	editor := &lineEditor{in: bufio.NewReader(strings.NewReader(commands)), out: &out, complete: s.completions}
+	s.run(statements, editor, files)
+	return out.String()
+}
+
+func TestDebugSession(t *testing.T) {
+	const source = "var total = 0;\nfun add(n) {\n  total = total + n;\n}\nadd(1);\nadd(2);\nprint total;"
+	// The empty command repeats bt.
+	got := debugTranscript(t, source, "b 3\nc\np n\nbt\n\nc\nlocals\nc\n")
+	want := `Debugging test.yapl. Type help for a list of commands.
+Stopped at test.yapl:1 (entry)
+->    1  var total = 0;
+(ydb) Breakpoint at test.yapl:3
+(ydb) Stopped at test.yapl:3 (breakpoint)
+->    3    total = total + n;
+(ydb) n = 1
+(ydb) * #0  add at test.yapl:3
+  #1  <script> at test.yapl:5
+(ydb) * #0  add at test.yapl:3
+  #1  <script> at test.yapl:5
+(ydb) Stopped at test.yapl:3 (breakpoint)
+->    3    total = total + n;
+(ydb) Locals:
+  n = 2
+Globals:
+  total = 1
+  add = <fn add>
+(ydb) 3
+Program exited.
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDebugSessionEnds(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		commands string
+		want     string
+	}{
+		{"runtime error", "print 1;\nprint -\"a\";", "c\n", "(ydb) 1\nRuntime error: Operand must be a number."},
+		{"quit", "print 1;\nprint 2;", "n\nq\n", "(ydb) 1\nStopped at test.yapl:2 (step)\n->    2  print 2;\n(ydb) Program exited.\n"},
+		{"end of input", "print 1;", "", "(ydb) Program exited.\n"},
+		{"unknown command", "print 1;", "jump\nbreak\nb 9\nc\n", "(ydb) Unknown command 'jump'. Type help for a list.\n(ydb) Usage: break <line>\n(ydb) '9' is not a line of test.yapl.\n(ydb) 1\nProgram exited.\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := debugTranscript(t, test.source, test.commands); !strings.Contains(got, test.want) {
+				t.Errorf("got:\n%s\nwant it to contain:\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+// Package debugger runs a program on the tree-walking interpreter under the
+// control of a front end, such as the CLI's --debug mode or a Debug Adapter
+// Protocol server. It stops before statements at line breakpoints or after
+// a step, and while it is stopped the front end can look at the call stack
+// and the environments of each call.
+//
+// The program runs on its own goroutine. Whenever it stops, or finishes,
+// an Event is sent on Events; the front end then calls Continue or one of
+// the step methods to let it go on. The interpreter must not be touched
+// while the program is running.
+package debugger
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+
+	interpreter "github.com/shubhdevelop/YAPL/Interpreter"
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/ast"
+	"github.com/shubhdevelop/YAPL/environment"
+)
+
+// Reason says why the program stopped.
+type Reason string
+
+const (
+	// Entry is the stop before the first statement, if asked for.
+	Entry      Reason = "entry"
+	Breakpoint Reason = "breakpoint"
+	Step       Reason = "step"
+	// Pause is a stop asked for with Pause while the program ran.
+	Pause Reason = "pause"
+	// Exited is sent once, when the program has finished.
+	Exited Reason = "exited"
+)
+
+// Event is sent when the program stops or finishes. At is the first token
+// of the statement about to run. Err is the runtime error that ended the
+// program, if one did.
+type Event struct {
+	Reason Reason
+	At     token.Token
+	Err    error
+}
+
+// mode is what the program does when it is let go.
+type mode int
+
+const (
+	modeRun mode = iota
+	modeStepIn
+	modeStepOver
+	modeStepOut
+	modeQuit
+)
+
+// errQuit unwinds the program when the front end ends the session.
+var errQuit = errors.New("debugger: quit")
+
+// Debugger controls one run of a program.
+type Debugger struct {
+	Interpreter *interpreter.Interpreter
+
+	events  chan Event
+	resume  chan mode
+	current token.Token
+
+	// mu guards what the front end may change while the program runs.
+	mu          sync.Mutex
+	breakpoints map[int]bool
+	mode        mode
+	// stepDepth is the call depth the last step started at.
+	stepDepth int
+	// entry and pause ask the program to stop at the next statement, for
+	// the reason they are named after.
+	entry  bool
+	pause  bool
+	paused bool
+	// lastLine, lastDepth and lastStart are where the last statement ran,
+	// so that a breakpoint stops once on a line with several statements but
+	// again each time a loop comes back to the line.
+	lastLine  int
+	lastDepth int
+	lastStart int
+}
+
+func New(interpreter *interpreter.Interpreter) *Debugger {
+	d := &Debugger{
+		Interpreter: interpreter,
+		events:      make(chan Event, 1),
+		resume:      make(chan mode),
+		breakpoints: map[int]bool{},
+	}
+	interpreter.DebugHook = d.hook
+	return d
+}
+
+// Events delivers an Event each time the program stops, and an Exited one
+// when it finishes, after which the channel is closed.
+func (d *Debugger) Events() <-chan Event {
+	return d.events
+}
+
+// Start runs statements on a new goroutine. If stopOnEntry is set the
+// program stops before its first statement.
+func (d *Debugger) Start(statements []ast.Stmt, stopOnEntry bool) {
+	d.mu.Lock()
+	d.entry = stopOnEntry
+	d.mu.Unlock()
+	go func() {
+		_, err := d.Interpreter.Execute(statements)
+		if errors.Is(err, errQuit) {
+			err = nil
+		}
+		d.events <- Event{Reason: Exited, Err: err}
+		close(d.events)
+	}()
+}
+
+// hook is the interpreter's DebugHook. Blocks aren't stopped at, since
+// their first statement comes straight after, and neither are statements
+// with no position to show.
+func (d *Debugger) hook(stmt ast.Stmt) {
+	if _, ok := stmt.(ast.BlockStmt); ok {
+		return
+	}
+	at, ok := ast.StmtToken(stmt)
+	if !ok {
+		return
+	}
+	depth := d.Interpreter.CallDepth()
+
+	d.mu.Lock()
+	if d.mode == modeQuit {
+		d.mu.Unlock()
+		panic(errQuit)
+	}
+	reason, stop := d.shouldStop(at, depth)
+	d.lastLine, d.lastDepth, d.lastStart = at.Line, depth, at.Start
+	if stop {
+		d.paused = true
+		d.entry, d.pause = false, false
+	}
+	d.mu.Unlock()
+	if !stop {
+		return
+	}
+
+	d.current = at
+	d.events <- Event{Reason: reason, At: at}
+	next := <-d.resume
+
+	d.mu.Lock()
+	d.paused = false
+	d.mode = next
+	d.stepDepth = depth
+	d.mu.Unlock()
+	if next == modeQuit {
+		panic(errQuit)
+	}
+}
+
+func (d *Debugger) shouldStop(at token.Token, depth int) (Reason, bool) {
+	switch {
+	case d.entry:
+		return Entry, true
+	case d.pause:
+		return Pause, true
+	case d.mode == modeStepIn,
+		d.mode == modeStepOver && depth <= d.stepDepth,
+		d.mode == modeStepOut && depth < d.stepDepth:
+		return Step, true
+	case d.breakpoints[at.Line] && !d.sameLine(at, depth):
+		return Breakpoint, true
+	}
+	return "", false
+}
+
+// sameLine reports whether the statement at at carries on the line the last
+// one ran on. Statements on a line run left to right, so one that starts
+// no later than the last has been come back to by a loop.
+func (d *Debugger) sameLine(at token.Token, depth int) bool {
+	return at.Line == d.lastLine && depth == d.lastDepth && at.Start > d.lastStart
+}
+
+// let resumes a stopped program. It does nothing if the program isn't
+// stopped.
+func (d *Debugger) let(next mode) {
+	d.mu.Lock()
+	paused := d.paused
+	d.mu.Unlock()
+	if paused {
+		d.resume <- next
+	}
+}
+
+// Continue runs until a breakpoint or the end.
+func (d *Debugger) Continue() { d.let(modeRun) }
+
+// StepIn stops at the next statement, inside a call if it makes one.
+func (d *Debugger) StepIn() { d.let(modeStepIn) }
+
+// StepOver stops at the next statement that isn't inside a call made by
+// the current one.
+func (d *Debugger) StepOver() { d.let(modeStepOver) }
+
+// StepOut stops at the next statement after the current call returns.
+func (d *Debugger) StepOut() { d.let(modeStepOut) }
+
+// Pause stops a running program at its next statement.
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	d.pause = true
+	d.mu.Unlock()
+}
+
+// Quit ends the program at its next statement, or straight away if it is
+// stopped. The Exited event follows as usual.
+func (d *Debugger) Quit() {
+	d.mu.Lock()
+	paused := d.paused
+	d.mode = modeQuit
+	d.mu.Unlock()
+	if paused {
+		d.resume <- modeQuit
+	}
+}
+
+// SetBreakpoints replaces the line breakpoints.
+func (d *Debugger) SetBreakpoints(lines []int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints = map[int]bool{}
+	for _, line := range lines {
+		d.breakpoints[line] = true
+	}
+}
+
+// Breakpoints returns the lines with breakpoints, in order.
+func (d *Debugger) Breakpoints() []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	lines := make([]int, 0, len(d.breakpoints))
+	for line := range d.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// Stack returns the active calls of a stopped program, innermost first.
+func (d *Debugger) Stack() []interpreter.StackFrame {
+	frames := d.Interpreter.Frames(d.current)
+	for left, right := 0, len(frames)-1; left < right; left, right = left+1, right-1 {
+		frames[left], frames[right] = frames[right], frames[left]
+	}
+	return frames
+}
+
+// Scope is one environment in a chain, named for showing.
+type Scope struct {
+	Name        string
+	Environment *environment.Environment
+}
+
+// Scopes lists the environment chain starting at env: "Locals", then
+// "Enclosing 1", "Enclosing 2" and so on, and "Globals" last.
+func (d *Debugger) Scopes(env *environment.Environment) []Scope {
+	var scopes []Scope
+	for depth := 0; env != nil; depth++ {
+		name := "Enclosing " + strconv.Itoa(depth)
+		switch {
+		case env == d.Interpreter.Globals:
+			name = "Globals"
+		case depth == 0:
+			name = "Locals"
+		}
+		scopes = append(scopes, Scope{Name: name, Environment: env})
+		env = env.Enclosing
+	}
+	return scopes
+}
+
+// Variable is a binding, with its value formatted for showing.
+type Variable struct {
+	Name  string
+	Value string
+}
+
+// Variables lists the bindings of env in the order they were defined,
+// leaving out the native functions every program starts with.
+func Variables(env *environment.Environment) []Variable {
+	var variables []Variable
+	for _, name := range env.Names() {
+		value, _ := env.Lookup(name)
+		if _, ok := value.(*interpreter.NativeFunction); ok {
+			continue
+		}
+		variables = append(variables, Variable{Name: name, Value: Describe(value)})
+	}
+	return variables
+}
+
+// Lookup finds name in the environment chain starting at env.
+func Lookup(env *environment.Environment, name string) (interface{}, bool) {
+	for ; env != nil; env = env.Enclosing {
+		if value, ok := env.Lookup(name); ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// Describe formats value like print does, but with strings quoted.
+func Describe(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return strconv.Quote(text)
+	}
+	return interpreter.Stringify(value)
+}
@@ -0,0 +1,58 @@
+package debugger
+
+import (
+	"io"
+	"testing"
+
+	interpreter "github.com/shubhdevelop/YAPL/Interpreter"
+	"github.com/shubhdevelop/YAPL/frontend"
+)
+
+// stops runs source with breakpoints on lines and returns the line of each
+// stop, continuing after every one.
+func stops(t *testing.T, source string, lines ...int) []int {
+	t.Helper()
+	result := frontend.Parse(source, frontend.Options{})
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors)
+	}
+
+	interpreter := interpreter.NewInterpreter()
+	interpreter.Stdout = io.Discard
+	debugger := New(interpreter)
+	debugger.SetBreakpoints(lines)
+	debugger.Start(result.Statements, false)
+	var stopped []int
+	for event := range debugger.Events() {
+		if event.Reason == Exited {
+			if event.Err != nil {
+				t.Fatal(event.Err)
+			}
+			continue
+		}
+		stopped = append(stopped, event.At.Line)
+		debugger.Continue()
+	}
+	return stopped
+}
+
+func TestBreakpointStopsOnEachIteration(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   int
+		want   int
+	}{
+		{"body on its own line", "var i = 0;\nwhile (i < 3) {\n  i = i + 1;\n}", 3, 3},
+		{"loop on one line", "var i = 0;\nwhile (i < 3) i = i + 1;", 2, 3},
+		{"several statements on the line", "var i = 0;\nwhile (i < 3) {\n  print i; i = i + 1;\n}", 3, 3},
+		{"no loop", "var a = 1; var b = 2;\nprint a + b;", 1, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := stops(t, test.source, test.line); len(got) != test.want {
+				t.Errorf("stopped on lines %v, want %d stops", got, test.want)
+			}
+		})
+	}
+}
@@ -6,9 +6,17 @@ import (
 	"github.com/shubhdevelop/YAPL/YaplErrors"
 )
 
+// Environment holds the bindings of one scope. The global scope keeps them
+// by name in Values, since globals are looked up by name. Every other scope
+// keeps them in Slots, in declaration order, for the resolved (depth, slot)
+// lookups the interpreter performs on locals, and has a nil Values.
 type Environment struct {
 	Enclosing *Environment
 	Values    map[string]interface{}
+	Slots     []interface{}
+	// names are the names defined in the scope, in the order they were
+	// first defined. For a local scope names[slot] names Slots[slot].
+	names []string
 }
 
 // Equivalent to Environment() in Java
@@ -23,16 +31,52 @@ func NewEnvironment() *Environment {
 func NewEnclosedEnvironment(enclosing *Environment) *Environment {
 	return &Environment{
 		Enclosing: enclosing,
-		Values:    make(map[string]interface{}),
 	}
 }
 
 func (e *Environment) Define(name string, value interface{}) {
+	if e.Values == nil {
+		e.Slots = append(e.Slots, value)
+		e.names = append(e.names, name)
+		return
+	}
+	if _, ok := e.Values[name]; !ok {
+		e.names = append(e.names, name)
+	}
 	e.Values[name] = value
 }
 
+// Names returns the names defined in e, in the order they were first
+// defined.
+func (e *Environment) Names() []string {
+	return append([]string(nil), e.names...)
+}
+
+// slot returns the slot name was defined in, in a local scope.
+func (e *Environment) slot(name string) (int, bool) {
+	for slot := len(e.names) - 1; slot >= 0; slot-- {
+		if e.names[slot] == name {
+			return slot, true
+		}
+	}
+	return 0, false
+}
+
+// Lookup returns the value name is bound to in e itself, not looking at
+// the scopes that enclose it.
+func (e *Environment) Lookup(name string) (interface{}, bool) {
+	if e.Values != nil {
+		value, ok := e.Values[name]
+		return value, ok
+	}
+	if slot, ok := e.slot(name); ok {
+		return e.Slots[slot], true
+	}
+	return nil, false
+}
+
 func (e *Environment) Get(name token.Token) (interface{}, error) {
-	if value, exists := e.Values[name.Lexeme]; exists {
+	if value, exists := e.Lookup(name.Lexeme); exists {
 		return value, nil
 	}
 
@@ -49,8 +93,13 @@ func (e *Environment) Get(name token.Token) (interface{}, error) {
 }
 
 func (e *Environment) Assign(name token.Token, value interface{}) {
-	if _, ok := e.Values[name.Lexeme]; ok {
-		e.Values[name.Lexeme] = value
+	if e.Values != nil {
+		if _, ok := e.Values[name.Lexeme]; ok {
+			e.Values[name.Lexeme] = value
+			return
+		}
+	} else if slot, ok := e.slot(name.Lexeme); ok {
+		e.Slots[slot] = value
 		return
 	}
 
@@ -63,6 +112,22 @@ func (e *Environment) Assign(name token.Token, value interface{}) {
 		Token:   name,
 		Message: "Undefined variable '" + name.Lexeme + "'.",
 	}
-	error.ThrowRuntimeError()
+	panic(error.ThrowRuntimeError())
+}
+
+func (e *Environment) ancestor(distance int) *Environment {
+	environment := e
+	for i := 0; i < distance; i++ {
+		environment = environment.Enclosing
+	}
+	return environment
+}
+
+// GetAt reads the binding the resolver placed distance scopes up at slot.
+func (e *Environment) GetAt(distance, slot int) interface{} {
+	return e.ancestor(distance).Slots[slot]
+}
 
+func (e *Environment) AssignAt(distance, slot int, value interface{}) {
+	e.ancestor(distance).Slots[slot] = value
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/shubhdevelop/YAPL/formatter"
+)
+
+// runFmt implements 'Lox fmt', which formats YAPL files, and returns the
+// exit status. Without files it formats standard input.
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flags.Bool("check", false, "list files that aren't formatted and exit with status 1 if there are any, without changing them")
+	write := flags.Bool("write", false, "rewrite files in place instead of printing them")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: Lox fmt [--check] [--write] [files]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		source, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		formatted, err := formatter.Format(string(source))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "<stdin>:", err)
+			return 65
+		}
+		if *check {
+			if formatted != string(source) {
+				fmt.Println("<stdin>")
+				return 1
+			}
+			return 0
+		}
+		fmt.Print(formatted)
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		formatted, err := formatter.Format(string(source))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			status = 65
+			continue
+		}
+		changed := formatted != string(source)
+		if *check && changed {
+			fmt.Println(path)
+			if status == 0 {
+				status = 1
+			}
+		}
+		if *write && changed {
+			if err := os.WriteFile(path, []byte(formatted), 0o644); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				status = 1
+			}
+		}
+		if !*check && !*write {
+			fmt.Print(formatted)
+		}
+	}
+	return status
+}
@@ -0,0 +1,324 @@
+// Package formatter rewrites YAPL source in the canonical layout: one
+// statement per line, blocks indented by two spaces with the opening brace
+// on the line that starts them, single spaces around binary operators and
+// after commas, and at most one blank line in a row. Comments stay where
+// they were, and line breaks inside a statement are kept as continuation
+// lines.
+//
+// It works on the token stream rather than the syntax tree, because the
+// parser turns for loops into while loops and drops comments.
+package formatter
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/frontend"
+	"github.com/shubhdevelop/YAPL/parser"
+)
+
+// indentUnit is one level of indentation.
+const indentUnit = "  "
+
+// Format returns source in the canonical layout. Source that doesn't scan
+// or parse is not formatted; the syntax errors are returned instead.
+func Format(source string) (string, error) {
+	if strings.TrimSpace(source) == "" {
+		return "", nil
+	}
+	tokens, err := scan(source)
+	if err != nil {
+		return "", err
+	}
+	f := &formatter{tokens: tokens, statementEnd: true}
+	formatted := f.format()
+
+	// The layout must never change what the program means.
+	check, err := scan(formatted)
+	if err != nil || !sameTokens(tokens, check) {
+		return "", errors.New("formatter: formatting changed the program; this is a bug")
+	}
+	return formatted, nil
+}
+
+// scan returns the tokens of source, comments included, after making sure
+// it parses.
+func scan(source string) ([]token.Token, error) {
+	result := frontend.Parse(source, frontend.Options{Trivia: true, NoResolve: true})
+	if len(result.Errors) > 0 {
+		return nil, errors.Join(result.Errors...)
+	}
+	return result.Tokens, nil
+}
+
+// sameTokens reports whether a and b are the same tokens, comments
+// included, apart from their positions.
+func sameTokens(a, b []token.Token) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx].Type != b[idx].Type || strings.TrimRight(a[idx].Lexeme, " \t\r") != strings.TrimRight(b[idx].Lexeme, " \t\r") {
+			return false
+		}
+	}
+	return true
+}
+
+// formatter lays out one token stream.
+type formatter struct {
+	tokens []token.Token
+	out    strings.Builder
+	// current is the index in tokens of the token being written.
+	current int
+
+	// indent is the number of blocks the next statement is nested in.
+	indent int
+	// braces has an entry for every open brace: whether it opened a block
+	// rather than a map literal, and the value of brackets when it did.
+	braces []brace
+	// brackets counts the parentheses, square brackets and map braces open
+	// in the current statement, for continuation lines.
+	brackets int
+
+	// prev is the last token written other than a comment, and prevBlock
+	// whether it was a brace that opened or closed a block.
+	prev      *token.Token
+	prevBlock bool
+	// prevUnary is set when prev is a unary operator.
+	prevUnary bool
+	// lastLine is the source line the last token written ended on.
+	lastLine int
+	// statementEnd is set when the next token starts a new statement or
+	// closes a block, so it must start a line at the block's indentation.
+	statementEnd bool
+	// lineEnded is set after a comment, which runs to the end of the line.
+	lineEnded bool
+	// emptyBlock is set straight after a block's opening brace, so an
+	// empty block is written as {}.
+	emptyBlock bool
+}
+
+type brace struct {
+	block    bool
+	brackets int
+}
+
+func (f *formatter) format() string {
+	for idx := range f.tokens {
+		tok := f.tokens[idx]
+		f.current = idx
+		switch tok.Type {
+		case token.EOF:
+		case token.COMMENT:
+			f.comment(tok)
+		default:
+			f.token(tok)
+		}
+	}
+	return f.out.String() + "\n"
+}
+
+func (f *formatter) comment(tok token.Token) {
+	text := strings.TrimRight(tok.Lexeme, " \t\r")
+	if f.out.Len() > 0 && !f.lineEnded && tok.Line == f.lastLine {
+		// A comment after code on the same line stays there.
+		f.out.WriteString(" " + text)
+	} else {
+		if f.out.Len() > 0 {
+			f.newline(tok, f.statementEnd)
+		}
+		if f.statementEnd || f.out.Len() == 0 {
+			f.writeIndent(f.indent)
+		} else {
+			f.writeIndent(f.indent + f.continuation(tok))
+		}
+		f.out.WriteString(text)
+	}
+	f.emptyBlock = false
+	f.lineEnded = true
+	f.lastLine = tok.Line
+}
+
+func (f *formatter) token(tok token.Token) {
+	closesBlock := tok.Type == token.RIGHT_BRACE && len(f.braces) > 0 && f.braces[len(f.braces)-1].block
+	opensBlock := tok.Type == token.LEFT_BRACE && f.startsBlock()
+	if closesBlock {
+		f.indent--
+		f.statementEnd = true
+	}
+	if f.prevBlock && f.prev.Type == token.RIGHT_BRACE && !f.lineEnded &&
+		(tok.Type == token.ELSE || tok.Type == token.CATCH || tok.Type == token.FINALLY) {
+		// } else {
+		f.statementEnd = false
+	}
+
+	switch {
+	case f.out.Len() == 0:
+	case closesBlock && f.emptyBlock:
+		// {}
+	case f.statementEnd:
+		f.newline(tok, true)
+		f.writeIndent(f.indent)
+	case f.lineEnded || (tok.Line > f.lastLine && f.mayBreakBefore(tok, opensBlock)):
+		f.newline(tok, false)
+		f.writeIndent(f.indent + f.continuation(tok))
+	case f.spaceBefore(tok):
+		f.out.WriteString(" ")
+	}
+	f.out.WriteString(tok.Lexeme)
+
+	f.statementEnd = false
+	f.lineEnded = false
+	f.emptyBlock = false
+	f.prevUnary = (tok.Type == token.MINUS || tok.Type == token.BANG) && !f.endsOperand()
+	f.prevBlock = opensBlock || closesBlock
+	f.prev = &tok
+	f.lastLine = tok.Line + strings.Count(tok.Lexeme, "\n")
+
+	switch tok.Type {
+	case token.LEFT_BRACE:
+		f.braces = append(f.braces, brace{block: opensBlock, brackets: f.brackets})
+		if opensBlock {
+			f.indent++
+			f.brackets = 0
+			f.statementEnd = true
+			f.emptyBlock = true
+		} else {
+			f.brackets++
+		}
+	case token.RIGHT_BRACE:
+		if len(f.braces) > 0 {
+			f.brackets = f.braces[len(f.braces)-1].brackets
+			f.braces = f.braces[:len(f.braces)-1]
+		}
+		if closesBlock {
+			f.statementEnd = true
+		}
+	case token.LEFT_PAREN, token.LEFT_BRACKET:
+		f.brackets++
+	case token.RIGHT_PAREN, token.RIGHT_BRACKET:
+		if f.brackets > 0 {
+			f.brackets--
+		}
+	case token.SEMICOLON:
+		// Semicolons inside a for loop's parentheses don't end a line.
+		if f.brackets == 0 {
+			f.statementEnd = true
+		}
+	}
+}
+
+// newline ends the current line, leaving a blank line before tok if the
+// source had one there and tok starts a statement other than the first
+// or last in a block.
+func (f *formatter) newline(tok token.Token, statement bool) {
+	f.out.WriteString("\n")
+	if statement && tok.Line-f.lastLine > 1 && !f.emptyBlock && tok.Type != token.RIGHT_BRACE {
+		f.out.WriteString("\n")
+	}
+}
+
+func (f *formatter) writeIndent(level int) {
+	f.out.WriteString(strings.Repeat(indentUnit, level))
+}
+
+// continuation is how much deeper than the statement a line that starts
+// with tok inside it is indented: one level per open bracket, and at least
+// one unless tok closes the brackets.
+func (f *formatter) continuation(tok token.Token) int {
+	depth := f.brackets
+	switch tok.Type {
+	case token.RIGHT_PAREN, token.RIGHT_BRACKET, token.RIGHT_BRACE:
+		if depth > 0 {
+			return depth - 1
+		}
+		return 0
+	}
+	if depth == 0 {
+		return 1
+	}
+	return depth
+}
+
+// mayBreakBefore reports whether a line break the source has before tok
+// is kept. Braces that open blocks and the keywords that follow a block
+// always stay on the line before, as do semicolons and commas.
+func (f *formatter) mayBreakBefore(tok token.Token, opensBlock bool) bool {
+	if opensBlock {
+		return false
+	}
+	switch tok.Type {
+	case token.SEMICOLON, token.COMMA, token.COLON:
+		return false
+	case token.ELSE, token.CATCH, token.FINALLY:
+		return !(f.prevBlock && f.prev.Type == token.RIGHT_BRACE)
+	}
+	return true
+}
+
+// startsBlock reports whether a left brace written now opens a block
+// rather than a map literal. Blocks follow the header of a statement, a
+// class name or another statement; maps appear where an expression can.
+// Where a statement can start, the brace is decided the way the parser
+// decides it.
+func (f *formatter) startsBlock() bool {
+	if f.prev == nil || f.statementEnd {
+		return !parser.OpensMap(f.tokens, f.current)
+	}
+	switch f.prev.Type {
+	case token.RIGHT_PAREN, token.ELSE:
+		// The body of an if or a loop, or a function's
+		return !parser.OpensMap(f.tokens, f.current)
+	case token.TRY, token.FINALLY, token.IDENTIFIER:
+		return true
+	case token.LEFT_BRACE, token.RIGHT_BRACE:
+		return f.prevBlock
+	}
+	return false
+}
+
+// endsOperand reports whether prev can end an operand, so that a minus
+// after it is binary and a bracket after it starts a call or an index.
+func (f *formatter) endsOperand() bool {
+	if f.prev == nil {
+		return false
+	}
+	switch f.prev.Type {
+	case token.NUMBER, token.STRING, token.IDENTIFIER, token.TRUE, token.FALSE, token.NIL,
+		token.THIS, token.RIGHT_PAREN, token.RIGHT_BRACKET:
+		return true
+	case token.RIGHT_BRACE:
+		return !f.prevBlock
+	}
+	return false
+}
+
+// spaceBefore reports whether tok is separated from prev on the same line.
+func (f *formatter) spaceBefore(tok token.Token) bool {
+	switch tok.Type {
+	case token.SEMICOLON, token.COMMA, token.RIGHT_PAREN, token.RIGHT_BRACKET, token.DOT, token.COLON:
+		return false
+	case token.RIGHT_BRACE:
+		return f.prevBlock
+	}
+	if f.prevUnary {
+		return false
+	}
+	switch f.prev.Type {
+	case token.LEFT_PAREN, token.LEFT_BRACKET, token.DOT:
+		return false
+	case token.LEFT_BRACE:
+		return f.prevBlock
+	}
+	switch tok.Type {
+	case token.LEFT_PAREN:
+		// A call, or a function's parameters
+		return !(f.prev.Type == token.IDENTIFIER || f.prev.Type == token.RIGHT_PAREN || f.prev.Type == token.RIGHT_BRACKET)
+	case token.LEFT_BRACKET:
+		// An index, or a list literal
+		return !f.endsOperand()
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package formatter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGolden formats each testdata/*.yapl file and compares the result with
+// the .golden file next to it. Formatting a golden file must not change it.
+func TestGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.yapl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no files in testdata")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden, err := os.ReadFile(strings.TrimSuffix(path, ".yapl") + ".golden")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			formatted, err := Format(string(source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatted != string(golden) {
+				t.Errorf("got:\n%s\nwant:\n%s", formatted, golden)
+			}
+
+			again, err := Format(string(golden))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again != string(golden) {
+				t.Errorf("formatting the golden file changed it:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestSyntaxErrorsAreNotFormatted(t *testing.T) {
+	if formatted, err := Format("print ;"); err == nil {
+		t.Fatalf("formatted %q, want a syntax error", formatted)
+	}
+}
@@ -0,0 +1,74 @@
+// Package frontend runs the stages every YAPL tool starts with: it scans
+// source, parses the tokens and resolves the syntax tree, collecting the
+// errors of all three.
+package frontend
+
+import (
+	"github.com/shubhdevelop/YAPL/Scanner"
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/ast"
+	"github.com/shubhdevelop/YAPL/parser"
+	"github.com/shubhdevelop/YAPL/resolver"
+)
+
+// Options changes how Parse treats source. The zero value scans, parses
+// and resolves it, collecting errors silently.
+type Options struct {
+	// File is the FileID of the source in Reporter's FileSet, for error
+	// snippets and runtime traces.
+	File token.FileID
+	// Reporter, if set, is told about each error as it is found, so it can
+	// print them. Otherwise a Reporter without Output collects them.
+	Reporter *yaplErrors.Reporter
+	// Trivia keeps comments in Result.Tokens. The parser never sees them.
+	Trivia bool
+	// OptionalFinalSemicolon lets the last statement leave out its
+	// semicolon, as at the REPL prompt.
+	OptionalFinalSemicolon bool
+	// NoResolve stops after parsing, for tools that only look at the shape
+	// of the program.
+	NoResolve bool
+}
+
+// Result is what Parse found.
+type Result struct {
+	// Statements holds whatever parsed, even when there were errors.
+	Statements []ast.Stmt
+	Tokens     []token.Token
+	// Errors are the errors of every stage in the order they were found,
+	// mostly yaplErrors.SyntaxError values. The tree is only resolved if
+	// scanning and parsing found none.
+	Errors []error
+}
+
+// Parse scans, parses and resolves source.
+func Parse(source string, opts Options) Result {
+	reporter := opts.Reporter
+	if reporter == nil {
+		reporter = &yaplErrors.Reporter{}
+	}
+	reported := len(reporter.Errors)
+
+	scanner := scanner.Scanner{Source: source, File: opts.File, Reporter: reporter, Trivia: opts.Trivia}
+	tokens, err := scanner.ScanTokens()
+	if err != nil {
+		return Result{Errors: []error{err}}
+	}
+	code := tokens
+	if opts.Trivia {
+		code = nil
+		for _, tok := range tokens {
+			if tok.Type != token.COMMENT {
+				code = append(code, tok)
+			}
+		}
+	}
+
+	parser := parser.Parser{Tokens: code, Reporter: reporter, OptionalFinalSemicolon: opts.OptionalFinalSemicolon}
+	statements, _ := parser.Parse()
+	if len(reporter.Errors) == reported && !opts.NoResolve {
+		resolver.NewResolver(reporter).Resolve(statements)
+	}
+	return Result{Statements: statements, Tokens: tokens, Errors: reporter.Errors[reported:]}
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// errInterrupted is returned by readLine when the user presses Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+// maxHistory is how many lines of history are kept, in memory and in the
+// history file.
+const maxHistory = 1000
+
+// historyFileName is the history file, in the user's home directory.
+const historyFileName = ".yapl_history"
+
+// lineEditor reads the REPL's input. On a terminal it offers cursor
+// movement, history that persists between sessions, reverse search with
+// Ctrl-R and tab completion. Otherwise, as when input is piped in, it
+// reads plain lines.
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	fd       int
+	terminal bool
+	// complete returns the words that could finish word, the partial word
+	// before the cursor.
+	complete    func(word string) []string
+	history     []string
+	historyFile string
+}
+
+func newLineEditor(complete func(word string) []string) *lineEditor {
+	e := &lineEditor{
+		in:       bufio.NewReader(os.Stdin),
+		out:      os.Stdout,
+		fd:       int(os.Stdin.Fd()),
+		complete: complete,
+	}
+	e.terminal = isTerminal(e.fd)
+	if e.terminal {
+		if home, err := os.UserHomeDir(); err == nil {
+			e.historyFile = filepath.Join(home, historyFileName)
+			e.loadHistory()
+		}
+	}
+	return e
+}
+
+// loadHistory reads the history file, trimming it to maxHistory lines.
+func (e *lineEditor) loadHistory() {
+	data, err := os.ReadFile(e.historyFile)
+	if err != nil {
+		return
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+		os.WriteFile(e.historyFile, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+	}
+	for _, line := range lines {
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+}
+
+// addHistory remembers line, unless it repeats the previous entry, and
+// appends it to the history file.
+func (e *lineEditor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[1:]
+	}
+	if e.historyFile == "" {
+		return
+	}
+	file, err := os.OpenFile(e.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, line)
+}
+
+// readLine shows prompt and returns the line the user enters, without its
+// newline. It returns io.EOF at the end of input or on Ctrl-D on an empty
+// line, and errInterrupted on Ctrl-C.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	if e.terminal {
+		if restore, err := makeRaw(e.fd); err == nil {
+			defer restore()
+			line, err := e.edit(prompt)
+			if err == nil {
+				e.addHistory(line)
+			}
+			return line, err
+		}
+	}
+
+	fmt.Fprint(e.out, prompt)
+	line, err := e.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Keys the editor handles, as read in raw mode.
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// Escape sequences are reported as these, outside the range of runes.
+const (
+	keyUp = unicode.MaxRune + 1 + iota
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+// editState is one line being edited.
+type editState struct {
+	*lineEditor
+	prompt string
+	line   []rune
+	cursor int
+	// historyIndex is the history entry being shown; len(history) is the
+	// new line, which is kept in draft while browsing older ones.
+	historyIndex int
+	draft        []rune
+}
+
+func (e *lineEditor) edit(prompt string) (string, error) {
+	s := &editState{lineEditor: e, prompt: prompt, historyIndex: len(e.history)}
+	s.refresh()
+	for {
+		key, err := s.readKey()
+		if err != nil {
+			return "", err
+		}
+		if key == keyCtrlR {
+			if key, err = s.search(); err != nil {
+				return "", err
+			}
+		}
+		switch key {
+		case keyEnter, '\n':
+			// Redraw first in case the line came from a search.
+			s.refresh()
+			fmt.Fprint(s.out, "\r\n")
+			return string(s.line), nil
+		case keyCtrlC:
+			fmt.Fprint(s.out, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(s.line) == 0 {
+				fmt.Fprint(s.out, "\r\n")
+				return "", io.EOF
+			}
+			s.deleteAt(s.cursor)
+		case keyDelete:
+			s.deleteAt(s.cursor)
+		case keyBackspace, keyCtrlH:
+			if s.cursor > 0 {
+				s.cursor--
+				s.deleteAt(s.cursor)
+			}
+		case keyLeft, keyCtrlB:
+			if s.cursor > 0 {
+				s.cursor--
+			}
+		case keyRight, keyCtrlF:
+			if s.cursor < len(s.line) {
+				s.cursor++
+			}
+		case keyHome, keyCtrlA:
+			s.cursor = 0
+		case keyEnd, keyCtrlE:
+			s.cursor = len(s.line)
+		case keyUp, keyCtrlP:
+			s.showHistory(s.historyIndex - 1)
+		case keyDown, keyCtrlN:
+			s.showHistory(s.historyIndex + 1)
+		case keyCtrlK:
+			s.line = s.line[:s.cursor]
+		case keyCtrlU:
+			s.line = s.line[s.cursor:]
+			s.cursor = 0
+		case keyCtrlW:
+			start := s.wordStart(unicode.IsSpace)
+			s.line = append(s.line[:start], s.line[s.cursor:]...)
+			s.cursor = start
+		case keyCtrlL:
+			fmt.Fprint(s.out, "\033[H\033[2J")
+		case keyTab:
+			s.completeWord()
+		case keyCtrlG, keyUnknown:
+		default:
+			if key >= ' ' && key <= unicode.MaxRune {
+				s.insert(key)
+			}
+		}
+		s.refresh()
+	}
+}
+
+// readKey reads one key press, decoding the escape sequences of the arrow
+// and editing keys.
+func (s *editState) readKey() (rune, error) {
+	key, _, err := s.in.ReadRune()
+	if err != nil || key != keyEscape {
+		return key, err
+	}
+	next, _, err := s.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil
+	}
+	code, _, err := s.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	switch code {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	}
+	if code < '0' || code > '9' {
+		return keyUnknown, nil
+	}
+	// ESC [ <number> ~, as sent by Home, End and Delete
+	number := string(code)
+	for {
+		ch, _, err := s.in.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		if ch == '~' {
+			break
+		}
+		number += string(ch)
+	}
+	switch number {
+	case "1", "7":
+		return keyHome, nil
+	case "4", "8":
+		return keyEnd, nil
+	case "3":
+		return keyDelete, nil
+	}
+	return keyUnknown, nil
+}
+
+// refresh redraws the prompt and line and puts the cursor back.
+func (s *editState) refresh() {
+	s.draw(s.prompt, s.line, s.cursor)
+}
+
+func (s *editState) draw(prompt string, line []rune, cursor int) {
+	fmt.Fprintf(s.out, "\r%s%s\033[K", prompt, string(line))
+	if back := len(line) - cursor; back > 0 {
+		fmt.Fprintf(s.out, "\033[%dD", back)
+	}
+}
+
+func (s *editState) insert(text ...rune) {
+	line := make([]rune, 0, len(s.line)+len(text))
+	line = append(line, s.line[:s.cursor]...)
+	line = append(line, text...)
+	s.line = append(line, s.line[s.cursor:]...)
+	s.cursor += len(text)
+}
+
+func (s *editState) deleteAt(idx int) {
+	if idx < len(s.line) {
+		s.line = append(s.line[:idx], s.line[idx+1:]...)
+	}
+}
+
+// wordStart returns where the word before the cursor begins, a word being
+// a run of runes for which separator is false.
+func (s *editState) wordStart(separator func(rune) bool) int {
+	start := s.cursor
+	for start > 0 && separator(s.line[start-1]) {
+		start--
+	}
+	for start > 0 && !separator(s.line[start-1]) {
+		start--
+	}
+	return start
+}
+
+// showHistory replaces the line with history entry idx.
+func (s *editState) showHistory(idx int) {
+	if idx < 0 || idx > len(s.history) {
+		return
+	}
+	if s.historyIndex == len(s.history) {
+		s.draft = s.line
+	}
+	s.historyIndex = idx
+	if idx == len(s.history) {
+		s.line = s.draft
+	} else {
+		s.line = []rune(s.history[idx])
+	}
+	s.cursor = len(s.line)
+}
+
+// search runs a reverse incremental search through the history, started
+// by Ctrl-R. Typing narrows the search and Ctrl-R again finds an older
+// match. Any other key leaves the match on the line and is returned to be
+// handled as usual, so Enter runs the match and an arrow key starts
+// editing it. Ctrl-G cancels the search.
+func (s *editState) search() (rune, error) {
+	var query []rune
+	match := len(s.history)
+	found := true
+	original, originalCursor := s.line, s.cursor
+
+	// find looks for query in the entries before from, newest first.
+	find := func(from int) {
+		for idx := from - 1; idx >= 0; idx-- {
+			if position := strings.Index(s.history[idx], string(query)); position >= 0 {
+				match = idx
+				s.line = []rune(s.history[idx])
+				s.cursor = len([]rune(s.history[idx][:position]))
+				found = true
+				return
+			}
+		}
+		found = false
+	}
+
+	for {
+		label := "(reverse-i-search)"
+		if !found {
+			label = "(failed reverse-i-search)"
+		}
+		s.draw(fmt.Sprintf("%s'%s': ", label, string(query)), s.line, s.cursor)
+
+		key, err := s.readKey()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case key == keyCtrlR:
+			find(match)
+		case key == keyBackspace || key == keyCtrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(len(s.history))
+			}
+		case key == keyCtrlG:
+			s.line, s.cursor = original, originalCursor
+			return keyCtrlG, nil
+		case key >= ' ' && key <= unicode.MaxRune:
+			// The current match may still match the longer query.
+			query = append(query, key)
+			find(min(match+1, len(s.history)))
+		default:
+			if found && match < len(s.history) {
+				s.historyIndex = match
+			}
+			return key, nil
+		}
+	}
+}
+
+// completeWord completes the word before the cursor. A single candidate is
+// inserted in full. Several are completed as far as they agree, and listed
+// if that adds nothing.
+func (s *editState) completeWord() {
+	if s.complete == nil {
+		return
+	}
+	start := s.wordStart(func(ch rune) bool {
+		return !(ch == '_' || ch == ':' || unicode.IsLetter(ch) || unicode.IsDigit(ch))
+	})
+	word := string(s.line[start:s.cursor])
+	if word == "" {
+		return
+	}
+	candidates := s.complete(word)
+	if len(candidates) == 0 {
+		return
+	}
+	prefix := candidates[0]
+	for _, candidate := range candidates[1:] {
+		for !strings.HasPrefix(candidate, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(candidates) == 1 {
+		prefix += " "
+	}
+	if len(prefix) > len(word) {
+		s.insert([]rune(prefix[len(word):])...)
+		return
+	}
+	fmt.Fprint(s.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Key sequences as a terminal in raw mode sends them.
+const (
+	up        = "\x1b[A"
+	down      = "\x1b[B"
+	right     = "\x1b[C"
+	left      = "\x1b[D"
+	deleteKey = "\x1b[3~"
+	backspace = "\x7f"
+)
+
+// editor returns a lineEditor that reads keys from an in-memory terminal
+// and writes its drawing to out.
+func editor(keys string, out *bytes.Buffer, history ...string) *lineEditor {
+	return &lineEditor{
+		in:      bufio.NewReader(strings.NewReader(keys)),
+		out:     out,
+		history: history,
+		complete: func(word string) []string {
+			var words []string
+			for _, candidate := range []string{"print", "private", "var"} {
+				if strings.HasPrefix(candidate, word) {
+					words = append(words, candidate)
+				}
+			}
+			return words
+		},
+	}
+}
+
+func TestEditKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"typing", "abc\r", "abc"},
+		{"newline ends the line", "abc\n", "abc"},
+		{"left arrow", "abc" + left + left + "X\r", "aXbc"},
+		{"right arrow stops at the end", "ab" + left + right + right + "X\r", "abX"},
+		{"home and end", "abc\x01X\x05Y\r", "XabcY"},
+		{"home and end sequences", "abc\x1b[1~X\x1b[4~Y\r", "XabcY"},
+		{"backspace", "abc" + backspace + "\r", "ab"},
+		{"backspace at the start", "abc\x01" + backspace + "\r", "abc"},
+		{"delete", "abc\x01" + deleteKey + "\r", "bc"},
+		{"ctrl-d deletes under the cursor", "abc\x01\x04\r", "bc"},
+		{"ctrl-k kills to the end", "abc\x02\x02\x0b\r", "a"},
+		{"ctrl-u kills to the start", "abc\x02\x15\r", "c"},
+		{"ctrl-w deletes a word", "print hello  \x17\r", "print "},
+		{"runes", "héllo" + backspace + backspace + "\r", "hél"},
+		{"unknown sequences are ignored", "a\x1b[Zb\x1bxc\r", "abc"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			line, err := editor(test.keys, &out).edit("> ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line != test.want {
+				t.Errorf("got %q, want %q", line, test.want)
+			}
+		})
+	}
+}
+
+func TestEditEnds(t *testing.T) {
+	tests := []struct {
+		name string
+		keys string
+		want error
+	}{
+		{"ctrl-c", "abc\x03", errInterrupted},
+		{"ctrl-d on an empty line", "\x04", io.EOF},
+		{"end of input", "abc", io.EOF},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if _, err := editor(test.keys, &out).edit("> "); !errors.Is(err, test.want) {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestEditHistory(t *testing.T) {
+	history := []string{"first", "second"}
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"up shows the newest", up + "\r", "second"},
+		{"up twice", up + up + "\r", "first"},
+		{"up stops at the oldest", "\x10\x10\x10\r", "first"},
+		{"down returns to the draft", "draft" + up + up + down + down + "\r", "draft"},
+		{"down past the draft", "draft" + down + "\r", "draft"},
+		{"an entry can be edited", up + backspace + "\r", "secon"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			line, err := editor(test.keys, &out, history...).edit("> ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line != test.want {
+				t.Errorf("got %q, want %q", line, test.want)
+			}
+		})
+	}
+}
+
+func TestEditSearch(t *testing.T) {
+	history := []string{"print 1;", "var x = 2;", "print x;"}
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"finds the newest match", "\x12print\r", "print x;"},
+		{"ctrl-r again finds an older one", "\x12print\x12\r", "print 1;"},
+		{"narrowing keeps a match that still fits", "\x12pr\x12int 1\r", "print 1;"},
+		{"backspace widens the search", "\x12varz" + backspace + "\r", "var x = 2;"},
+		{"ctrl-g cancels", "typed\x12print\x07\r", "typed"},
+		{"an arrow starts editing the match", "\x12x =" + right + "X\r", "var xX = 2;"},
+		{"no match keeps the line", "typed\x12zzz\r", "typed"},
+		{"enter accepts the match", "\x12var\r", "var x = 2;"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			line, err := editor(test.keys, &out, history...).edit("> ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line != test.want {
+				t.Errorf("got %q, want %q", line, test.want)
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	if _, err := editor("\x12zzz\r", &out, history...).edit("> "); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "(failed reverse-i-search)'zzz': ") {
+		t.Errorf("a failed search drew %q", out.String())
+	}
+
+	out.Reset()
+	line, err := editor("\x12var"+up+"\r", &out, history...).edit("> ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "print 1;" {
+		t.Errorf("up after a search showed %q, want the entry before the match", line)
+	}
+}
+
+func TestEditCompletion(t *testing.T) {
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"one candidate is completed with a space", "v\t1\r", "var 1"},
+		{"several are completed as far as they agree", "p\t\r", "pri"},
+		{"inside a line", "(pri)" + left + "\t\r", "(pri)"},
+		{"no word", "\t\r", ""},
+		{"no candidates", "zz\t\r", "zz"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			line, err := editor(test.keys, &out).edit("> ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line != test.want {
+				t.Errorf("got %q, want %q", line, test.want)
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	if _, err := editor("pri\t\r", &out).edit("> "); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "\r\nprint  private\r\n") {
+		t.Errorf("ambiguous completion drew %q, want the candidates listed", out.String())
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), historyFileName)
+	var lines []string
+	for i := 0; i < maxHistory+5; i++ {
+		lines = append(lines, "line "+strconv.Itoa(i))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &lineEditor{historyFile: path}
+	e.loadHistory()
+	if len(e.history) != maxHistory || e.history[0] != "line 5" {
+		t.Fatalf("loaded %d entries starting with %q", len(e.history), e.history[0])
+	}
+
+	e.addHistory("print 1;")
+	e.addHistory("print 1;")
+	e.addHistory("   ")
+	if got := e.history[len(e.history)-1]; got != "print 1;" || len(e.history) != maxHistory {
+		t.Errorf("history ends with %q and has %d entries", got, len(e.history))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(saved) != maxHistory+1 || saved[0] != "line 5" || saved[len(saved)-1] != "print 1;" {
+		t.Errorf("history file has %d lines from %q to %q", len(saved), saved[0], saved[len(saved)-1])
+	}
+}
+
+// Without a terminal the editor reads plain lines.
+func TestReadLineWithoutTerminal(t *testing.T) {
+	var out bytes.Buffer
+	e := &lineEditor{in: bufio.NewReader(strings.NewReader("print 1;\r\nlast")), out: &out}
+	for _, want := range []string{"print 1;", "last"} {
+		line, err := e.readLine("> ")
+		if err != nil || line != want {
+			t.Fatalf("got %q, %v, want %q", line, err, want)
+		}
+	}
+	if _, err := e.readLine("> "); err != io.EOF {
+		t.Fatalf("got %v at the end of input, want io.EOF", err)
+	}
+	if out.String() != "> > > " {
+		t.Errorf("prompts drawn as %q", out.String())
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/shubhdevelop/YAPL/linter"
+)
+
+// fileDiagnostic is a finding in the JSON output of 'Lox lint'.
+type fileDiagnostic struct {
+	File string `json:"file"`
+	linter.Diagnostic
+}
+
+// runLint implements 'Lox lint', which reports suspicious code in YAPL
+// files, and returns the exit status: 1 if anything was found. Without
+// files it checks standard input.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the findings as a JSON array")
+	disable := flags.String("disable", "", "a comma-separated list of rules to turn off")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: Lox lint [--json] [--disable rules] [files]")
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), "rules:")
+		for _, rule := range linter.Rules {
+			fmt.Fprintf(flags.Output(), "  %-20s %s\n", rule.ID, rule.Description)
+		}
+	}
+	flags.Parse(args)
+
+	var disabled []string
+	for _, rule := range strings.Split(*disable, ",") {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			disabled = append(disabled, rule)
+		}
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	status := 0
+	findings := []fileDiagnostic{}
+	for _, path := range paths {
+		var source []byte
+		var err error
+		if path == "-" {
+			path = "<stdin>"
+			source, err = io.ReadAll(os.Stdin)
+		} else {
+			source, err = os.ReadFile(path)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+		diagnostics, err := linter.Lint(string(source), disabled...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			status = 65
+			continue
+		}
+		for _, diagnostic := range diagnostics {
+			findings = append(findings, fileDiagnostic{File: path, Diagnostic: diagnostic})
+		}
+	}
+
+	if err := writeFindings(os.Stdout, findings, *asJSON); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(findings) > 0 && status == 0 {
+		status = 1
+	}
+	return status
+}
+
+// writeFindings prints findings to w, one per line or as a JSON array.
+func writeFindings(w io.Writer, findings []fileDiagnostic, asJSON bool) error {
+	if asJSON {
+		data, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	for _, finding := range findings {
+		if _, err := fmt.Fprintf(w, "%s:%s\n", finding.File, finding.Diagnostic); err != nil {
+			return err
+		}
+	}
+	return nil
+}
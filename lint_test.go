@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/shubhdevelop/YAPL/linter"
+)
+
+func TestWriteFindings(t *testing.T) {
+	diagnostics, err := linter.Lint("{\n  var a = 1;\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings := []fileDiagnostic{}
+	for _, diagnostic := range diagnostics {
+		findings = append(findings, fileDiagnostic{File: "a.yapl", Diagnostic: diagnostic})
+	}
+
+	var text bytes.Buffer
+	if err := writeFindings(&text, findings, false); err != nil {
+		t.Fatal(err)
+	}
+	if want := "a.yapl:2:7: 'a' is declared but never used. (unused-variable)\n"; text.String() != want {
+		t.Errorf("text output %q, want %q", text.String(), want)
+	}
+
+	var out bytes.Buffer
+	if err := writeFindings(&out, findings, true); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("%v in %s", err, out.String())
+	}
+	want := map[string]interface{}{
+		"file":    "a.yapl",
+		"rule":    "unused-variable",
+		"message": "'a' is declared but never used.",
+		"line":    2.0,
+		"column":  7.0,
+	}
+	if len(decoded) != 1 || len(decoded[0]) != len(want) {
+		t.Fatalf("JSON output %s", out.String())
+	}
+	for key, value := range want {
+		if decoded[0][key] != value {
+			t.Errorf("%q is %v, want %v", key, decoded[0][key], value)
+		}
+	}
+
+	out.Reset()
+	if err := writeFindings(&out, []fileDiagnostic{}, true); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "[]\n" {
+		t.Errorf("JSON output without findings %q, want an empty array", out.String())
+	}
+}
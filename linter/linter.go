@@ -0,0 +1,465 @@
+// Package linter finds code that is legal but probably a mistake: unused
+// variables, variables that shadow others, unreachable statements,
+// conditions that never change and assignments of a variable to itself.
+//
+// Each finding has a rule ID. Rules can be turned off for a whole run, for
+// a file with a '// lint:disable <rule>...' comment anywhere in it, or for
+// one line with a '// lint:ignore <rule>...' comment at the end of it or on
+// the line before. A directive without rule IDs covers every rule.
+package linter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/ast"
+	"github.com/shubhdevelop/YAPL/frontend"
+	"github.com/shubhdevelop/YAPL/resolver"
+)
+
+// Rule IDs
+const (
+	UnusedVariable    = "unused-variable"
+	ShadowedVariable  = "shadowed-variable"
+	UnreachableCode   = "unreachable-code"
+	ConstantCondition = "constant-condition"
+	SelfAssignment    = "self-assignment"
+)
+
+// Rule describes one of the checks.
+type Rule struct {
+	ID          string
+	Description string
+}
+
+// Rules lists every rule.
+var Rules = []Rule{
+	{UnusedVariable, "a local variable is declared but never read"},
+	{ShadowedVariable, "a variable in a nested scope has the same name as one outside it"},
+	{UnreachableCode, "a statement follows break, continue, return or throw in the same block"},
+	{ConstantCondition, "an if or while condition is made only of literals (while (true) is allowed)"},
+	{SelfAssignment, "a variable, property or element is assigned to itself"},
+}
+
+// Diagnostic is one finding.
+type Diagnostic struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", d.Line, d.Column, d.Message, d.Rule)
+}
+
+// Lint checks source and returns its findings in source order, except for
+// rules named in disabled or turned off by comments. Source with syntax
+// or resolution errors isn't checked; the errors are returned instead.
+func Lint(source string, disabled ...string) ([]Diagnostic, error) {
+	result := frontend.Parse(source, frontend.Options{Trivia: true, NoResolve: true})
+	if len(result.Errors) > 0 {
+		return nil, errors.Join(result.Errors...)
+	}
+	reporter := &yaplErrors.Reporter{}
+	resolver := resolver.NewResolver(reporter)
+	resolver.Resolve(result.Statements)
+	if reporter.HadError() {
+		return nil, errors.Join(reporter.Errors...)
+	}
+
+	directives := newDirectives(disabled)
+	for _, tok := range result.Tokens {
+		if tok.Type == token.COMMENT {
+			directives.add(tok)
+		}
+	}
+
+	l := &linter{}
+	l.statements(result.Statements)
+	l.variables(resolver)
+
+	var diagnostics []Diagnostic
+	for _, diagnostic := range l.diagnostics {
+		if !directives.suppresses(diagnostic) {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	sort.SliceStable(diagnostics, func(a, b int) bool {
+		if diagnostics[a].Line != diagnostics[b].Line {
+			return diagnostics[a].Line < diagnostics[b].Line
+		}
+		return diagnostics[a].Column < diagnostics[b].Column
+	})
+	return diagnostics, nil
+}
+
+// directives records which rules are off, for the file and by line. The
+// empty rule ID stands for every rule.
+type directives struct {
+	file  map[string]bool
+	lines map[int]map[string]bool
+}
+
+func newDirectives(disabled []string) *directives {
+	d := &directives{file: map[string]bool{}, lines: map[int]map[string]bool{}}
+	for _, rule := range disabled {
+		d.file[rule] = true
+	}
+	return d
+}
+
+// add reads a comment, which may be a lint:disable or lint:ignore
+// directive.
+func (d *directives) add(comment token.Token) {
+	words := strings.Fields(strings.TrimPrefix(comment.Lexeme, "//"))
+	if len(words) == 0 {
+		return
+	}
+	rules := words[1:]
+	if len(rules) == 0 {
+		rules = []string{""}
+	}
+	switch words[0] {
+	case "lint:disable":
+		for _, rule := range rules {
+			d.file[rule] = true
+		}
+	case "lint:ignore":
+		for _, line := range []int{comment.Line, comment.Line + 1} {
+			if d.lines[line] == nil {
+				d.lines[line] = map[string]bool{}
+			}
+			for _, rule := range rules {
+				d.lines[line][rule] = true
+			}
+		}
+	}
+}
+
+func (d *directives) suppresses(diagnostic Diagnostic) bool {
+	line := d.lines[diagnostic.Line]
+	return d.file[""] || d.file[diagnostic.Rule] || line[""] || line[diagnostic.Rule]
+}
+
+// linter walks a program for the rules that depend on its shape. The rules
+// about variables use what the resolver recorded instead.
+type linter struct {
+	diagnostics []Diagnostic
+}
+
+var _ ast.ExprVisitor = (*linter)(nil)
+var _ ast.StmtVisitor = (*linter)(nil)
+
+func (l *linter) report(rule string, at token.Token, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+		Line:    at.Line,
+		Column:  at.Column,
+	})
+}
+
+// variables reports the local var declarations that are never read or
+// that shadow another declaration. Globals are never reported: another
+// file, or the REPL, may use them.
+func (l *linter) variables(resolved *resolver.Resolver) {
+	read := map[*resolver.Declaration]bool{}
+	for _, use := range resolved.Uses() {
+		if !use.Assign {
+			read[use.Declaration] = true
+		}
+	}
+	for _, declaration := range resolved.Declarations() {
+		if declaration.Kind != resolver.VariableDeclaration || declaration.Global {
+			continue
+		}
+		name := declaration.Name
+		if declaration.Shadows != nil {
+			l.report(ShadowedVariable, name, "'%s' shadows the variable declared on line %d.", name.Lexeme, declaration.Shadows.Name.Line)
+		}
+		if !read[declaration] {
+			l.report(UnusedVariable, name, "'%s' is declared but never used.", name.Lexeme)
+		}
+	}
+}
+
+func (l *linter) expr(expr ast.Expr) {
+	if expr != nil {
+		expr.Accept(l)
+	}
+}
+
+func (l *linter) stmt(stmt ast.Stmt) {
+	if stmt != nil {
+		stmt.Accept(l)
+	}
+}
+
+// statements checks a list of statements that run one after another,
+// reporting the first that can't be reached.
+func (l *linter) statements(stmts []ast.Stmt) {
+	reported := false
+	for idx, stmt := range stmts {
+		l.stmt(stmt)
+		if reported || idx == len(stmts)-1 {
+			continue
+		}
+		if keyword, ok := jump(stmt); ok {
+			if at, ok := ast.StmtToken(stmts[idx+1]); ok {
+				l.report(UnreachableCode, at, "Unreachable code after '%s'.", keyword)
+				reported = true
+			}
+		}
+	}
+}
+
+// jump reports whether stmt always leaves the statements around it, and
+// with which keyword.
+func jump(stmt ast.Stmt) (string, bool) {
+	switch stmt.(type) {
+	case ast.BreakStmt:
+		return "break", true
+	case ast.ContinueStmt:
+		return "continue", true
+	case ast.ReturnStmt:
+		return "return", true
+	case ast.ThrowStmt:
+		return "throw", true
+	}
+	return "", false
+}
+
+// checkCondition reports a condition built only from literals. A literal
+// true is allowed in a while loop, which is how an endless loop is written
+// and what a for loop without a condition becomes.
+func (l *linter) checkCondition(keyword token.Token, condition ast.Expr) {
+	if literal, ok := condition.(ast.Literal); ok && literal.Value == true && keyword.Type != token.IF {
+		return
+	}
+	if constant(condition) {
+		l.report(ConstantCondition, keyword, "The condition of this '%s' never changes.", keyword.Lexeme)
+	}
+}
+
+// constant reports whether expr is built only from literals.
+func constant(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case ast.Literal:
+		return true
+	case ast.Grouping:
+		return constant(e.Expression)
+	case ast.Unary:
+		return constant(e.Right)
+	case ast.Binary:
+		return constant(e.Left) && constant(e.Right)
+	case ast.Logical:
+		return constant(e.Left) && constant(e.Right)
+	}
+	return false
+}
+
+// sameTarget reports whether a and b certainly name the same place: the
+// same variable, this, or the same property or element of one. Anything
+// with a call in it might not.
+func sameTarget(a, b ast.Expr) bool {
+	switch x := a.(type) {
+	case ast.Variable:
+		y, ok := b.(ast.Variable)
+		return ok && x.Name.Lexeme == y.Name.Lexeme
+	case ast.This:
+		_, ok := b.(ast.This)
+		return ok
+	case ast.Literal:
+		y, ok := b.(ast.Literal)
+		return ok && x.Value == y.Value
+	case ast.Get:
+		y, ok := b.(ast.Get)
+		return ok && x.Name.Lexeme == y.Name.Lexeme && sameTarget(x.Object, y.Object)
+	case ast.Index:
+		y, ok := b.(ast.Index)
+		return ok && sameTarget(x.Object, y.Object) && sameTarget(x.Index, y.Index)
+	}
+	return false
+}
+
+func (l *linter) VisitBlockStmtStmt(stmt ast.BlockStmt) interface{} {
+	l.statements(stmt.Statement)
+	return nil
+}
+
+func (l *linter) VisitExpressionStmtStmt(stmt ast.ExpressionStmt) interface{} {
+	l.expr(stmt.Expression)
+	return nil
+}
+
+func (l *linter) VisitIfStmtStmt(stmt ast.IfStmt) interface{} {
+	l.checkCondition(stmt.Keyword, stmt.Condition)
+	l.expr(stmt.Condition)
+	l.stmt(stmt.ThenBranch)
+	l.stmt(stmt.ElseBranch)
+	return nil
+}
+
+func (l *linter) VisitPrintStmtStmt(stmt ast.PrintStmt) interface{} {
+	l.expr(stmt.Expression)
+	return nil
+}
+
+func (l *linter) VisitVarStmtStmt(stmt ast.VarStmt) interface{} {
+	l.expr(stmt.Initializer)
+	return nil
+}
+
+func (l *linter) VisitWhileStmtStmt(stmt ast.WhileStmt) interface{} {
+	l.checkCondition(stmt.Keyword, stmt.Condition)
+	l.expr(stmt.Condition)
+	l.stmt(stmt.Body)
+	l.expr(stmt.Increment)
+	return nil
+}
+
+func (l *linter) VisitBreakStmtStmt(stmt ast.BreakStmt) interface{} {
+	return nil
+}
+
+func (l *linter) VisitContinueStmtStmt(stmt ast.ContinueStmt) interface{} {
+	return nil
+}
+
+func (l *linter) VisitFunctionStmtStmt(stmt ast.FunctionStmt) interface{} {
+	l.statements(stmt.Body)
+	return nil
+}
+
+func (l *linter) VisitReturnStmtStmt(stmt ast.ReturnStmt) interface{} {
+	l.expr(stmt.Value)
+	return nil
+}
+
+func (l *linter) VisitClassStmtStmt(stmt ast.ClassStmt) interface{} {
+	if stmt.Superclass != nil {
+		l.expr(*stmt.Superclass)
+	}
+	for _, method := range stmt.Methods {
+		l.statements(method.Body)
+	}
+	return nil
+}
+
+func (l *linter) VisitTryStmtStmt(stmt ast.TryStmt) interface{} {
+	l.statements(stmt.Body)
+	l.statements(stmt.CatchBody)
+	l.statements(stmt.FinallyBody)
+	return nil
+}
+
+func (l *linter) VisitThrowStmtStmt(stmt ast.ThrowStmt) interface{} {
+	l.expr(stmt.Value)
+	return nil
+}
+
+func (l *linter) VisitAssignExpr(expr ast.Assign) interface{} {
+	if value, ok := expr.Value.(ast.Variable); ok && value.Name.Lexeme == expr.Name.Lexeme {
+		l.report(SelfAssignment, expr.Name, "'%s' is assigned to itself.", expr.Name.Lexeme)
+	}
+	l.expr(expr.Value)
+	return nil
+}
+
+func (l *linter) VisitBinaryExpr(expr ast.Binary) interface{} {
+	l.expr(expr.Left)
+	l.expr(expr.Right)
+	return nil
+}
+
+func (l *linter) VisitCallExpr(expr ast.Call) interface{} {
+	l.expr(expr.Callee)
+	for _, argument := range expr.Arguments {
+		l.expr(argument)
+	}
+	return nil
+}
+
+func (l *linter) VisitGetExpr(expr ast.Get) interface{} {
+	l.expr(expr.Object)
+	return nil
+}
+
+func (l *linter) VisitGroupingExpr(expr ast.Grouping) interface{} {
+	l.expr(expr.Expression)
+	return nil
+}
+
+func (l *linter) VisitLiteralExpr(expr ast.Literal) interface{} {
+	return nil
+}
+
+func (l *linter) VisitLogicalExpr(expr ast.Logical) interface{} {
+	l.expr(expr.Left)
+	l.expr(expr.Right)
+	return nil
+}
+
+func (l *linter) VisitSetExpr(expr ast.Set) interface{} {
+	if value, ok := expr.Value.(ast.Get); ok && value.Name.Lexeme == expr.Name.Lexeme && sameTarget(expr.Object, value.Object) {
+		l.report(SelfAssignment, expr.Name, "'%s' is assigned to itself.", expr.Name.Lexeme)
+	}
+	l.expr(expr.Object)
+	l.expr(expr.Value)
+	return nil
+}
+
+func (l *linter) VisitSuperExpr(expr ast.Super) interface{} {
+	return nil
+}
+
+func (l *linter) VisitThisExpr(expr ast.This) interface{} {
+	return nil
+}
+
+func (l *linter) VisitUnaryExpr(expr ast.Unary) interface{} {
+	l.expr(expr.Right)
+	return nil
+}
+
+func (l *linter) VisitVariableExpr(expr ast.Variable) interface{} {
+	return nil
+}
+
+func (l *linter) VisitListExpr(expr ast.List) interface{} {
+	for _, element := range expr.Elements {
+		l.expr(element)
+	}
+	return nil
+}
+
+func (l *linter) VisitMapExpr(expr ast.Map) interface{} {
+	for idx, key := range expr.Keys {
+		l.expr(key)
+		l.expr(expr.Values[idx])
+	}
+	return nil
+}
+
+func (l *linter) VisitIndexExpr(expr ast.Index) interface{} {
+	l.expr(expr.Object)
+	l.expr(expr.Index)
+	return nil
+}
+
+func (l *linter) VisitIndexSetExpr(expr ast.IndexSet) interface{} {
+	if value, ok := expr.Value.(ast.Index); ok && sameTarget(expr.Object, value.Object) && sameTarget(expr.Index, value.Index) {
+		if at, ok := ast.ExprToken(expr.Object); ok {
+			l.report(SelfAssignment, at, "An element is assigned to itself.")
+		}
+	}
+	l.expr(expr.Object)
+	l.expr(expr.Index)
+	l.expr(expr.Value)
+	return nil
+}
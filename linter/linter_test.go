@@ -0,0 +1,149 @@
+package linter
+
+import (
+	"strings"
+	"testing"
+)
+
+// lint returns the findings for source as strings, failing the test on a
+// syntax error.
+func lint(t *testing.T, source string, disabled ...string) []string {
+	t.Helper()
+	diagnostics, err := Lint(source, disabled...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, diagnostic := range diagnostics {
+		found = append(found, diagnostic.String())
+	}
+	return found
+}
+
+type lintTest struct {
+	name   string
+	source string
+	want   []string
+}
+
+func runLintTests(t *testing.T, tests []lintTest) {
+	t.Helper()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := lint(t, test.source)
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnusedVariable(t *testing.T) {
+	runLintTests(t, []lintTest{
+		{"unused local", "{\n  var a = 1;\n}", []string{"2:7: 'a' is declared but never used. (unused-variable)"}},
+		{"read local", "{ var a = 1; print a; }", nil},
+		{"only assigned", "{ var a = 1; a = 2; }", []string{"1:7: 'a' is declared but never used. (unused-variable)"}},
+		{"read by a closure", "fun f() { var a = 1; fun g() { return a; } return g; }", nil},
+		{"globals", "var a = 1;", nil},
+		{"parameters", "fun f(a) {}", nil},
+		{"catch variable", "try { throw 1; } catch (e) {}", nil},
+		{"in declaration order", "{ var b = 1; var a = 2; }", []string{
+			"1:7: 'b' is declared but never used. (unused-variable)",
+			"1:18: 'a' is declared but never used. (unused-variable)",
+		}},
+	})
+}
+
+func TestShadowedVariable(t *testing.T) {
+	runLintTests(t, []lintTest{
+		{"shadows a global", "var a = 1;\n{ var a = 2; print a; }", []string{"2:7: 'a' shadows the variable declared on line 1. (shadowed-variable)"}},
+		{"shadows a parameter", "fun f(a) {\n  { var a = 2; print a; }\n}", []string{"2:9: 'a' shadows the variable declared on line 1. (shadowed-variable)"}},
+		{"redeclared global", "var a = 1; var a = 2;", nil},
+		{"sibling blocks", "{ var a = 1; print a; } { var a = 2; print a; }", nil},
+	})
+}
+
+func TestUnreachableCode(t *testing.T) {
+	runLintTests(t, []lintTest{
+		{"after return", "fun f() {\n  return 1;\n  print 2;\n  print 3;\n}", []string{"3:3: Unreachable code after 'return'. (unreachable-code)"}},
+		{"after break", "while (true) { break; print 1; }", []string{"1:23: Unreachable code after 'break'. (unreachable-code)"}},
+		{"after continue", "for (var i = 0; i < 1; i = i + 1) { continue; print i; }", []string{"1:47: Unreachable code after 'continue'. (unreachable-code)"}},
+		{"after throw", "throw 1; print 2;", []string{"1:10: Unreachable code after 'throw'. (unreachable-code)"}},
+		{"jump in a nested block", "fun f() { if (f) return 1; print 2; }", nil},
+		{"jump last", "fun f() { print 1; return 2; }", nil},
+	})
+}
+
+func TestConstantCondition(t *testing.T) {
+	runLintTests(t, []lintTest{
+		{"if literal", "if (true) print 1;", []string{"1:1: The condition of this 'if' never changes. (constant-condition)"}},
+		{"if expression of literals", "if (!(1 > 2) and nil) print 1;", []string{"1:1: The condition of this 'if' never changes. (constant-condition)"}},
+		{"while false", "while (false) print 1;", []string{"1:1: The condition of this 'while' never changes. (constant-condition)"}},
+		{"while true", "while (true) break;", nil},
+		{"endless for", "for (;;) break;", nil},
+		{"variable", "var a = 1; if (a > 2) print a;", nil},
+	})
+}
+
+func TestSelfAssignment(t *testing.T) {
+	runLintTests(t, []lintTest{
+		{"variable", "var a = 1; a = a;", []string{"1:12: 'a' is assigned to itself. (self-assignment)"}},
+		{"property", "class A { m() { this.x = this.x; } }", []string{"1:22: 'x' is assigned to itself. (self-assignment)"}},
+		{"element", "var l = [1]; l[0] = l[0];", []string{"1:14: An element is assigned to itself. (self-assignment)"}},
+		{"different element", "var l = [1, 2]; l[0] = l[1];", nil},
+		{"element through a call", "fun f() { return [1]; } f()[0] = f()[0];", nil},
+		{"different variable", "var a = 1; var b = 2; a = b;", nil},
+	})
+}
+
+func TestDirectives(t *testing.T) {
+	const unused = "{ var a = 1; }"
+	tests := []struct {
+		name     string
+		source   string
+		disabled []string
+		want     int
+	}{
+		{"no directive", unused, nil, 1},
+		{"ignore on the line", "{ var a = 1; } // lint:ignore unused-variable", nil, 0},
+		{"ignore on the line before", "// lint:ignore unused-variable\n" + unused, nil, 0},
+		{"ignore two lines before", "// lint:ignore unused-variable\n\n" + unused, nil, 1},
+		{"ignore another rule", "// lint:ignore self-assignment\n" + unused, nil, 1},
+		{"ignore every rule", "// lint:ignore\n" + unused, nil, 0},
+		{"ignore several rules", "// lint:ignore self-assignment unused-variable\n" + unused, nil, 0},
+		{"disable for the file", unused + "\n\n// lint:disable unused-variable", nil, 0},
+		{"disable every rule", "// lint:disable\n" + unused + "\nif (true) print 1;", nil, 0},
+		{"disable another rule", "// lint:disable shadowed-variable\n" + unused, nil, 1},
+		{"disabled for the run", unused, []string{UnusedVariable}, 0},
+		{"other rules disabled for the run", unused, []string{SelfAssignment}, 1},
+		{"not a directive", "// lint: ignore\n" + unused, nil, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := lint(t, test.source, test.disabled...); len(got) != test.want {
+				t.Errorf("got %q, want %d findings", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSourceOrder(t *testing.T) {
+	// Unused variables are found at the end of their scope, after the rest.
+	got := lint(t, "{\n  var a = 1;\n  var b = 2; b = b;\n}\nif (1) print 2;")
+	want := []string{
+		"2:7: 'a' is declared but never used. (unused-variable)",
+		"3:14: 'b' is assigned to itself. (self-assignment)",
+		"5:1: The condition of this 'if' never changes. (constant-condition)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSyntaxErrorsAreNotLinted(t *testing.T) {
+	for _, source := range []string{"{ var a = ; }", "{ var a = 1; var a = 2; }"} {
+		if diagnostics, err := Lint(source); err == nil {
+			t.Errorf("%q: got %v, want an error", source, diagnostics)
+		}
+	}
+}
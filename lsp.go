@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/shubhdevelop/YAPL/lsp"
+)
+
+// runLSP implements 'Lox lsp', which runs a language server for an editor
+// on standard input and output, and returns the exit status.
+func runLSP(args []string) int {
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: Lox lsp")
+		fmt.Fprintln(flags.Output(), "Speaks the Language Server Protocol on standard input and output.")
+	}
+	flags.Parse(args)
+
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,172 @@
+package lsp
+
+import (
+	"errors"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/frontend"
+	"github.com/shubhdevelop/YAPL/resolver"
+)
+
+// document is an open file and what the server learnt from it. It is
+// analysed again from scratch on every change.
+type document struct {
+	uri    string
+	source string
+	// lineStarts holds the byte offset each line starts at.
+	lineStarts []int
+
+	diagnostics []Diagnostic
+	// symbols are the top-level declarations, with what they declare
+	// nested under them.
+	symbols []*declaration
+	// occurrences are every declaration and use of a variable, in source
+	// order.
+	occurrences []occurrence
+}
+
+func newDocument(uri, source string) *document {
+	d := &document{uri: uri, source: source, lineStarts: []int{0}}
+	for idx := 0; idx < len(source); idx++ {
+		if source[idx] == '\n' {
+			d.lineStarts = append(d.lineStarts, idx+1)
+		}
+	}
+	d.analyse()
+	return d
+}
+
+// analyse scans, parses and resolves the source, keeping its errors as
+// diagnostics, and indexes whatever parsed.
+func (d *document) analyse() {
+	result := frontend.Parse(d.source, frontend.Options{NoResolve: true})
+	// Whatever parsed is resolved for the index, but resolution errors are
+	// only reported for source without syntax errors, as Parse would.
+	reporter := &yaplErrors.Reporter{}
+	resolver := resolver.NewResolver(reporter)
+	resolver.Resolve(result.Statements)
+	errs := result.Errors
+	if len(errs) == 0 {
+		errs = reporter.Errors
+	}
+
+	d.diagnostics = []Diagnostic{}
+	for _, err := range errs {
+		var syntaxError yaplErrors.SyntaxError
+		if errors.As(err, &syntaxError) {
+			d.diagnostics = append(d.diagnostics, Diagnostic{
+				Range:    d.errorRange(syntaxError, result.Tokens),
+				Severity: severityError,
+				Source:   "yapl",
+				Message:  syntaxError.Message,
+			})
+		}
+	}
+
+	d.symbols, d.occurrences = index(resolver)
+	sort.Slice(d.occurrences, func(a, b int) bool {
+		return d.occurrences[a].name.Start < d.occurrences[b].name.Start
+	})
+}
+
+// position converts a byte offset into the source to a protocol Position.
+func (d *document) position(offset int) Position {
+	if offset > len(d.source) {
+		offset = len(d.source)
+	}
+	line := sort.Search(len(d.lineStarts), func(idx int) bool { return d.lineStarts[idx] > offset }) - 1
+	return Position{Line: line, Character: utf16Length(d.source[d.lineStarts[line]:offset])}
+}
+
+// offset converts a protocol Position to a byte offset into the source,
+// clamping it to the document.
+func (d *document) offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lineStarts) {
+		return len(d.source)
+	}
+	offset := d.lineStarts[pos.Line]
+	for units := 0; offset < len(d.source) && d.source[offset] != '\n'; {
+		ch, size := utf8.DecodeRuneInString(d.source[offset:])
+		if units += utf16Width(ch); units > pos.Character {
+			break
+		}
+		offset += size
+	}
+	return offset
+}
+
+func (d *document) tokenRange(tok token.Token) Range {
+	return Range{Start: d.position(tok.Start), End: d.position(tok.End)}
+}
+
+// errorRange is where err points: the token it was reported at, the end of
+// the file, or the whole line when it has no column.
+func (d *document) errorRange(err yaplErrors.SyntaxError, tokens []token.Token) Range {
+	line := err.Line - 1
+	if line < 0 {
+		line = 0
+	}
+	if line >= len(d.lineStarts) {
+		line = len(d.lineStarts) - 1
+	}
+	lineStart := d.lineStarts[line]
+	lineEnd := len(d.source)
+	if line+1 < len(d.lineStarts) {
+		lineEnd = d.lineStarts[line+1] - 1
+	}
+	if err.Column == 0 {
+		return Range{Start: d.position(lineStart), End: d.position(lineEnd)}
+	}
+
+	// Column counts runes from 1.
+	start := lineStart
+	for n := 1; n < err.Column && start < lineEnd; n++ {
+		_, size := utf8.DecodeRuneInString(d.source[start:])
+		start += size
+	}
+	end := start
+	for _, tok := range tokens {
+		if tok.Start == start && tok.Type != token.EOF {
+			end = tok.End
+			break
+		}
+	}
+	if end == start && start < lineEnd && err.Where != " at end" {
+		_, size := utf8.DecodeRuneInString(d.source[start:])
+		end = start + size
+	}
+	return Range{Start: d.position(start), End: d.position(end)}
+}
+
+// occurrenceAt returns the declaration or use of a variable at pos.
+func (d *document) occurrenceAt(pos Position) (occurrence, bool) {
+	offset := d.offset(pos)
+	for _, occurrence := range d.occurrences {
+		// The cursor may sit just after the name.
+		if occurrence.name.Start <= offset && offset <= occurrence.name.End {
+			return occurrence, true
+		}
+	}
+	return occurrence{}, false
+}
+
+func utf16Length(text string) int {
+	units := 0
+	for _, ch := range text {
+		units += utf16Width(ch)
+	}
+	return units
+}
+
+func utf16Width(ch rune) int {
+	if ch >= 0x10000 {
+		return 2
+	}
+	return 1
+}
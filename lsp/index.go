@@ -0,0 +1,89 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/ast"
+	"github.com/shubhdevelop/YAPL/resolver"
+)
+
+// declaration is something a program names: a variable, function, class,
+// method, parameter or caught exception.
+type declaration struct {
+	name token.Token
+	kind SymbolKind
+	// signature is how hover shows the declaration.
+	signature string
+	// children are the declarations nested in this one that are listed as
+	// document symbols.
+	children []*declaration
+}
+
+// occurrence is a name in the source and the declaration it refers to.
+// The declaring name is an occurrence too.
+type occurrence struct {
+	name        token.Token
+	declaration *declaration
+}
+
+// index turns what the resolver recorded into the document's symbols, the
+// top-level declarations with what they declare nested under them, and
+// its occurrences, in no particular order.
+func index(resolved *resolver.Resolver) ([]*declaration, []occurrence) {
+	var symbols []*declaration
+	var occurrences []occurrence
+	declarations := map[*resolver.Declaration]*declaration{}
+	for _, found := range resolved.Declarations() {
+		kind, signature := describe(found)
+		declaration := &declaration{name: found.Name, kind: kind, signature: signature}
+		declarations[found] = declaration
+		occurrences = append(occurrences, occurrence{name: found.Name, declaration: declaration})
+
+		// Outlines don't show parameters or caught exceptions.
+		switch {
+		case found.Kind == resolver.ParameterDeclaration || found.Kind == resolver.CatchDeclaration:
+		case found.Parent == nil:
+			symbols = append(symbols, declaration)
+		default:
+			parent := declarations[found.Parent]
+			parent.children = append(parent.children, declaration)
+		}
+	}
+	for _, use := range resolved.Uses() {
+		occurrences = append(occurrences, occurrence{name: use.Name, declaration: declarations[use.Declaration]})
+	}
+	return symbols, occurrences
+}
+
+// describe returns the symbol kind of a declaration and how hover shows
+// it.
+func describe(found *resolver.Declaration) (SymbolKind, string) {
+	name := found.Name.Lexeme
+	switch found.Kind {
+	case resolver.FunctionDeclaration:
+		return symbolFunction, signature("fun ", found.Node.(ast.FunctionStmt))
+	case resolver.ClassDeclaration:
+		header := "class " + name
+		if class := found.Node.(ast.ClassStmt); class.Superclass != nil {
+			header += " < " + class.Superclass.Name.Lexeme
+		}
+		return symbolClass, header
+	case resolver.MethodDeclaration:
+		return symbolMethod, signature(found.Parent.Name.Lexeme+".", found.Node.(ast.FunctionStmt))
+	case resolver.ParameterDeclaration:
+		return symbolVariable, fmt.Sprintf("(parameter) %s of %s", name, found.Node.(ast.FunctionStmt).Name.Lexeme)
+	case resolver.CatchDeclaration:
+		return symbolVariable, fmt.Sprintf("catch (%s)", name)
+	}
+	return symbolVariable, "var " + name
+}
+
+func signature(keyword string, function ast.FunctionStmt) string {
+	params := make([]string, len(function.Params))
+	for idx, param := range function.Params {
+		params[idx] = param.Lexeme
+	}
+	return fmt.Sprintf("%s%s(%s)", keyword, function.Name.Lexeme, strings.Join(params, ", "))
+}
@@ -0,0 +1,291 @@
+// Package lsp is a Language Server Protocol server for YAPL, so editors can
+// show syntax errors as you type, jump to where a variable is declared,
+// find its uses, show its declaration on hover, outline a file and
+// complete keywords.
+//
+// The server talks JSON-RPC over a pair of streams, normally standard input
+// and output, and handles one message at a time. Documents are synced in
+// full on every change.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/shubhdevelop/YAPL/Scanner"
+)
+
+// Server is one connection to an editor.
+type Server struct {
+	conn      conn
+	documents map[string]*document
+	// initialized is set once the client has sent initialize, and
+	// shutdown once it has sent shutdown.
+	initialized bool
+	shutdown    bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		conn:      conn{in: bufio.NewReader(in), out: out},
+		documents: map[string]*document{},
+	}
+}
+
+// Serve handles messages until the client sends exit or the input ends.
+// It returns nil if the client shut the server down first, as the
+// protocol asks, and an error otherwise.
+func (s *Server) Serve() error {
+	for {
+		body, err := s.conn.read()
+		if err == io.EOF {
+			if s.shutdown {
+				return nil
+			}
+			return fmt.Errorf("lsp: input ended without a shutdown request")
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err := s.conn.write(message{ID: &nullID, Error: &responseError{Code: codeParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == "exit" {
+			if s.shutdown {
+				return nil
+			}
+			return fmt.Errorf("lsp: exit without a shutdown request")
+		}
+		if msg.ID == nil {
+			if err := s.notification(msg); err != nil {
+				return err
+			}
+			continue
+		}
+		result, rpcErr := s.request(msg)
+		response := message{ID: msg.ID, Error: rpcErr}
+		if rpcErr == nil {
+			if response.Result, err = json.Marshal(result); err != nil {
+				return err
+			}
+		}
+		if err := s.conn.write(response); err != nil {
+			return err
+		}
+	}
+}
+
+// nullID is the ID of a response to a message that couldn't be read.
+var nullID = json.RawMessage("null")
+
+// request handles a message that needs a response and returns its result.
+func (s *Server) request(msg message) (interface{}, *responseError) {
+	if msg.Method == "initialize" {
+		s.initialized = true
+		return s.initialize(), nil
+	}
+	if !s.initialized {
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "initialize has not been sent"}
+	}
+	if s.shutdown {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "the server is shutting down"}
+	}
+
+	switch msg.Method {
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	case "textDocument/references":
+		var params ReferenceParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.references(params), nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.documentSymbols(params), nil
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return completions(), nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+}
+
+// notification handles a message that gets no response. Ones the server
+// doesn't know are ignored, as the protocol allows.
+func (s *Server) notification(msg message) error {
+	if !s.initialized {
+		return nil
+	}
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if decodeParams(msg, &params) == nil {
+			return s.open(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if decodeParams(msg, &params) == nil && len(params.ContentChanges) > 0 {
+			return s.open(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if decodeParams(msg, &params) == nil {
+			delete(s.documents, params.TextDocument.URI)
+			// Clear the closed file's errors.
+			return s.publish(params.TextDocument.URI, []Diagnostic{})
+		}
+	}
+	return nil
+}
+
+func decodeParams(msg message, params interface{}) *responseError {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) initialize() InitializeResult {
+	var result InitializeResult
+	result.ServerInfo.Name = "yapl"
+	result.Capabilities.TextDocumentSync.OpenClose = true
+	result.Capabilities.TextDocumentSync.Change = syncFull
+	result.Capabilities.DefinitionProvider = true
+	result.Capabilities.ReferencesProvider = true
+	result.Capabilities.HoverProvider = true
+	result.Capabilities.DocumentSymbolProvider = true
+	result.Capabilities.CompletionProvider = &CompletionOptions{}
+	return result
+}
+
+// open analyses a document's new text and publishes its diagnostics.
+func (s *Server) open(uri, text string) error {
+	document := newDocument(uri, text)
+	s.documents[uri] = document
+	return s.publish(uri, document.diagnostics)
+}
+
+func (s *Server) publish(uri string, diagnostics []Diagnostic) error {
+	params, err := json.Marshal(PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+	if err != nil {
+		return err
+	}
+	return s.conn.write(message{Method: "textDocument/publishDiagnostics", Params: params})
+}
+
+// lookup returns the occurrence of a name at a position in an open
+// document.
+func (s *Server) lookup(params TextDocumentPositionParams) (*document, occurrence, bool) {
+	document := s.documents[params.TextDocument.URI]
+	if document == nil {
+		return nil, occurrence{}, false
+	}
+	occurrence, ok := document.occurrenceAt(params.Position)
+	return document, occurrence, ok
+}
+
+// definition returns where the name at a position is declared, or nil.
+func (s *Server) definition(params TextDocumentPositionParams) *Location {
+	document, occurrence, ok := s.lookup(params)
+	if !ok {
+		return nil
+	}
+	return &Location{URI: document.uri, Range: document.tokenRange(occurrence.declaration.name)}
+}
+
+// references returns every use of the variable named at a position.
+func (s *Server) references(params ReferenceParams) []Location {
+	locations := []Location{}
+	document, found, ok := s.lookup(params.TextDocumentPositionParams)
+	if !ok {
+		return locations
+	}
+	for _, occurrence := range document.occurrences {
+		if occurrence.declaration != found.declaration {
+			continue
+		}
+		if occurrence.name == occurrence.declaration.name && !params.Context.IncludeDeclaration {
+			continue
+		}
+		locations = append(locations, Location{URI: document.uri, Range: document.tokenRange(occurrence.name)})
+	}
+	return locations
+}
+
+// hover shows the declaration of the name at a position, or nothing.
+func (s *Server) hover(params TextDocumentPositionParams) *Hover {
+	document, occurrence, ok := s.lookup(params)
+	if !ok {
+		return nil
+	}
+	declaration := occurrence.declaration
+	return &Hover{
+		Contents: MarkupContent{
+			Kind:  "markdown",
+			Value: fmt.Sprintf("```yapl\n%s\n```\nDeclared on line %d.", declaration.signature, declaration.name.Line),
+		},
+		Range: document.tokenRange(occurrence.name),
+	}
+}
+
+func (s *Server) documentSymbols(params DocumentSymbolParams) []DocumentSymbol {
+	document := s.documents[params.TextDocument.URI]
+	if document == nil {
+		return []DocumentSymbol{}
+	}
+	return document.documentSymbols(document.symbols)
+}
+
+func (d *document) documentSymbols(declarations []*declaration) []DocumentSymbol {
+	symbols := make([]DocumentSymbol, len(declarations))
+	for idx, declaration := range declarations {
+		nameRange := d.tokenRange(declaration.name)
+		symbols[idx] = DocumentSymbol{
+			Name:           declaration.name.Lexeme,
+			Detail:         declaration.signature,
+			Kind:           declaration.kind,
+			Range:          nameRange,
+			SelectionRange: nameRange,
+			Children:       d.documentSymbols(declaration.children),
+		}
+	}
+	return symbols
+}
+
+// completions offers every keyword. The client filters them by what has
+// been typed.
+func completions() []CompletionItem {
+	items := make([]CompletionItem, 0, len(scanner.KeywordMap))
+	for keyword := range scanner.KeywordMap {
+		items = append(items, CompletionItem{Label: keyword, Kind: completionKeyword})
+	}
+	sort.Slice(items, func(a, b int) bool { return items[a].Label < items[b].Label })
+	return items
+}
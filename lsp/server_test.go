@@ -0,0 +1,212 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/shubhdevelop/YAPL/Scanner"
+)
+
+// client is the editor side of a connection to a Server running in the
+// test.
+type client struct {
+	t      *testing.T
+	conn   conn
+	input  *io.PipeWriter
+	nextID int
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	t.Helper()
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{
+		t:     t,
+		conn:  conn{in: bufio.NewReader(clientIn), out: clientOut},
+		input: clientOut,
+		done:  make(chan error, 1),
+	}
+	go func() {
+		err := NewServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+		c.done <- err
+	}()
+	t.Cleanup(func() { clientOut.Close() })
+	return c
+}
+
+func (c *client) send(method string, id *json.RawMessage, params interface{}) {
+	c.t.Helper()
+	raw, err := json.Marshal(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if err := c.conn.write(message{ID: id, Method: method, Params: raw}); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) receive() message {
+	c.t.Helper()
+	body, err := c.conn.read()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// call sends a request and decodes the result of its response into result.
+func (c *client) call(method string, params, result interface{}) {
+	c.t.Helper()
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	c.send(method, &id, params)
+	response := c.receive()
+	if response.Error != nil {
+		c.t.Fatalf("%s: %s", method, response.Error.Message)
+	}
+	if string(*response.ID) != string(id) {
+		c.t.Fatalf("%s: response has ID %s, want %s", method, *response.ID, id)
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	c.send(method, nil, params)
+}
+
+// diagnostics reads the diagnostics the server publishes after a change.
+func (c *client) diagnostics() PublishDiagnosticsParams {
+	c.t.Helper()
+	msg := c.receive()
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("got %q, want diagnostics", msg.Method)
+	}
+	var params PublishDiagnosticsParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		c.t.Fatal(err)
+	}
+	return params
+}
+
+const uri = "file:///test.yapl"
+
+const source = `var total = 0;
+fun add(a, b) {
+  return a + b;
+}
+print add(total, 1);
+`
+
+func at(line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: line, Character: character}}
+}
+
+func TestSession(t *testing.T) {
+	c := newClient(t)
+
+	var initialized InitializeResult
+	c.call("initialize", struct{}{}, &initialized)
+	if !initialized.Capabilities.DefinitionProvider || !initialized.Capabilities.HoverProvider {
+		t.Fatalf("capabilities %+v", initialized.Capabilities)
+	}
+
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, LanguageID: "yapl", Version: 1, Text: source}})
+	if published := c.diagnostics(); published.URI != uri || len(published.Diagnostics) != 0 {
+		t.Fatalf("diagnostics for a valid file: %+v", published)
+	}
+
+	var definition Location
+	c.call("textDocument/definition", at(4, 7), &definition)
+	want := Range{Start: Position{Line: 1, Character: 4}, End: Position{Line: 1, Character: 7}}
+	if definition.URI != uri || definition.Range != want {
+		t.Errorf("definition of add is %+v, want %+v", definition, want)
+	}
+
+	var hover Hover
+	c.call("textDocument/hover", at(4, 12), &hover)
+	if !strings.Contains(hover.Contents.Value, "total") || !strings.Contains(hover.Contents.Value, "Declared on line 1.") {
+		t.Errorf("hover on total shows %q", hover.Contents.Value)
+	}
+
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   TextDocumentIdentifier{URI: uri},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "print ;\n"}},
+	})
+	published := c.diagnostics()
+	if len(published.Diagnostics) != 1 {
+		t.Fatalf("diagnostics for a syntax error: %+v", published.Diagnostics)
+	}
+	if diagnostic := published.Diagnostics[0]; diagnostic.Severity != severityError || diagnostic.Range.Start.Line != 0 {
+		t.Errorf("diagnostic %+v", diagnostic)
+	}
+
+	var shutdown interface{}
+	c.call("shutdown", nil, &shutdown)
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// The server advertises completion without trigger characters or resolving,
+// so every item it returns must be complete: every keyword, once.
+func TestCompletion(t *testing.T) {
+	c := newClient(t)
+
+	var raw map[string]map[string]json.RawMessage
+	c.call("initialize", struct{}{}, &raw)
+	var options CompletionOptions
+	if err := json.Unmarshal(raw["capabilities"]["completionProvider"], &options); err != nil {
+		t.Fatalf("completionProvider %s: %v", raw["capabilities"]["completionProvider"], err)
+	}
+	if options.ResolveProvider || len(options.TriggerCharacters) > 0 {
+		t.Fatalf("completionProvider %+v", options)
+	}
+
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, LanguageID: "yapl", Version: 1, Text: source}})
+	c.diagnostics()
+
+	var items []CompletionItem
+	c.call("textDocument/completion", at(4, 0), &items)
+	labels := map[string]bool{}
+	for _, item := range items {
+		if item.Kind != completionKeyword || labels[item.Label] {
+			t.Errorf("item %+v", item)
+		}
+		labels[item.Label] = true
+	}
+	for keyword := range scanner.KeywordMap {
+		if !labels[keyword] {
+			t.Errorf("no item for %q", keyword)
+		}
+	}
+	if len(labels) != len(scanner.KeywordMap) {
+		t.Errorf("%d items for %d keywords", len(labels), len(scanner.KeywordMap))
+	}
+}
+
+func TestRequestBeforeInitialize(t *testing.T) {
+	c := newClient(t)
+	id := json.RawMessage("1")
+	c.send("textDocument/hover", &id, at(0, 0))
+	if response := c.receive(); response.Error == nil || response.Error.Code != codeServerNotInitialized {
+		t.Fatalf("got %+v, want a not initialized error", response)
+	}
+	c.input.Close()
+	if err := <-c.done; err == nil {
+		t.Fatal("Serve returned nil after input ended without a shutdown")
+	}
+}
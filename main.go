@@ -1,38 +1,114 @@
 package main
 
 import (
-	"bufio"
 	"errors"
+	"flag"
 	"fmt"
 	"os"
 
 	interpreter "github.com/shubhdevelop/YAPL/Interpreter"
-	"github.com/shubhdevelop/YAPL/Scanner"
-	"github.com/shubhdevelop/YAPL/environment"
-	"github.com/shubhdevelop/YAPL/parser"
-	"github.com/shubhdevelop/YAPL/state"
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/ast"
+	"github.com/shubhdevelop/YAPL/astjson"
+	"github.com/shubhdevelop/YAPL/compiler"
+	"github.com/shubhdevelop/YAPL/frontend"
+	"github.com/shubhdevelop/YAPL/printer"
+	"github.com/shubhdevelop/YAPL/resolver"
+	"github.com/shubhdevelop/YAPL/vm"
 )
 
-func run(source string) {
-	state.HadError = false // Reset error state
-	scanner := scanner.Scanner{Source: source}
-	tokens, err := scanner.ScanTokens()
-	interpreter := interpreter.Interpreter{
-		environment.NewEnvironment(),
+var useVM = flag.Bool("vm", false, "run programs on the bytecode VM instead of the tree-walking interpreter")
+var printAST = flag.Bool("print-ast", false, "print the program's syntax tree as S-expressions instead of running it")
+var printJSON = flag.Bool("print-json", false, "print the program's syntax tree as JSON instead of running it")
+var debug = flag.Bool("debug", false, "run the script under an interactive step debugger")
+var fromJSON = flag.Bool("from-json", false, "read the script as a syntax tree in the JSON form --print-json writes")
+
+// errCompile is returned by run when the source failed to scan, parse,
+// resolve or compile. The errors themselves have already been reported.
+var errCompile = errors.New("compile error")
+
+// parse scans, parses and resolves source, which was added to reporter's
+// FileSet as file. It returns errCompile if reporter saw any errors.
+// repl lets the last statement leave out its semicolon.
+func parse(source string, file token.FileID, reporter *yaplErrors.Reporter, repl bool) ([]ast.Stmt, error) {
+	result := frontend.Parse(source, frontend.Options{File: file, Reporter: reporter, OptionalFinalSemicolon: repl})
+	if result.Tokens == nil {
+		// The scanner refuses empty source without reporting it.
+		fmt.Println(errors.New("Error Scanning tokens"))
 	}
-	parserInstance := parser.Parser{
-		Tokens: tokens,
+	if len(result.Errors) > 0 {
+		return nil, errCompile
+	}
+	return result.Statements, nil
+}
+
+// run executes source, or prints its syntax tree with --print-ast or
+// --print-json, or debugs it with --debug. file names it in runtime error
+// traces.
+func run(source, file string) error {
+	files := &token.FileSet{}
+	reporter := &yaplErrors.Reporter{Output: os.Stderr, Files: files}
+	var statements []ast.Stmt
+	var err error
+	if *fromJSON {
+		statements, err = decode(source, reporter)
+	} else {
+		statements, err = parse(source, files.Add(file, source), reporter, false)
 	}
 	if err != nil {
-		fmt.Println(errors.New("Error Scanning tokens"))
-		state.HadError = true
-		return
+		return err
 	}
-	expr := parserInstance.Parse()
-	interpreter.Interpret(expr)
-	if state.HadError {
-		return
+
+	switch {
+	case *printAST:
+		// The tree as the parser leaves it, after turning for loops into
+		// while loops
+		astPrinter := &printer.AstPrinter{}
+		fmt.Println(astPrinter.PrintProgram(statements))
+		return nil
+	case *printJSON:
+		data, err := astjson.Encode(statements)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if *debug {
+		return runDebugger(statements, source, file, files)
 	}
+	if *useVM {
+		function := compiler.NewCompiler(reporter).Compile(statements)
+		if reporter.HadError() {
+			return errCompile
+		}
+		machine := vm.NewVM()
+		machine.File = file
+		machine.Files = files
+		return machine.Interpret(function)
+	}
+	interpreter := interpreter.NewInterpreter()
+	interpreter.File = file
+	interpreter.Files = files
+	return interpreter.Interpret(statements)
+}
+
+// decode rebuilds a program from the JSON form --print-json writes and
+// resolves it like parse does.
+func decode(source string, reporter *yaplErrors.Reporter) ([]ast.Stmt, error) {
+	statements, err := astjson.Decode([]byte(source))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, errCompile
+	}
+	resolver := resolver.NewResolver(reporter)
+	resolver.Resolve(statements)
+	if reporter.HadError() {
+		return nil, errCompile
+	}
+	return statements, nil
 }
 
 func runFile(path string) {
@@ -42,44 +118,37 @@ func runFile(path string) {
 	}
 	source := string(bytes[:])
 
-	run(source)
-	if state.HadError {
+	err = run(source, path)
+	if errors.Is(err, errCompile) {
 		os.Exit(65)
 	}
-	if state.HadRuntimeError {
+	if err != nil {
 		os.Exit(70)
 	}
 
 }
 
-func runPrompt() {
-	reader := bufio.NewReader(os.Stdin)
-
-	for {
-		fmt.Print(">> ")
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			fmt.Println("Error Reading the line")
-			continue
-		} else if line == "\n" || line == "" {
-			continue
-		} else if line == "clear\n" {
-			fmt.Print("\033[H\033[2J")
-			continue
-		} else if line == "exit\n" {
-			break
-		}
-		run(string(line))
-		if state.HadError {
-			state.HadError = false // Reset error state for next input
-		}
-	}
-}
-
 func main() {
-	args := os.Args[1:]
+	flag.Parse()
+	args := flag.Args()
+	if len(args) > 0 && args[0] == "fmt" {
+		os.Exit(runFmt(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "lint" {
+		os.Exit(runLint(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "lsp" {
+		os.Exit(runLSP(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "dap" {
+		os.Exit(runDAP(args[1:]))
+	}
+	if *debug && (*useVM || len(args) != 1) {
+		fmt.Fprintln(os.Stderr, "--debug needs a script and runs it on the tree-walking interpreter")
+		os.Exit(64)
+	}
 	if len(args) > 1 {
-		panic(errors.New("usage Lox [script]"))
+		panic(errors.New("usage Lox [--vm | --debug | --print-ast | --print-json] [--from-json] [script]"))
 	} else if len(args) == 1 {
 		runFile(args[0])
 	} else {
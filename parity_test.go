@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	interpreter "github.com/shubhdevelop/YAPL/Interpreter"
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/compiler"
+	"github.com/shubhdevelop/YAPL/vm"
+)
+
+// TestBackendsAgree runs the example scripts on the tree-walking
+// interpreter and on the bytecode VM and checks that they print the same
+// output and stop with the same runtime error.
+func TestBackendsAgree(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "parity", "*.yapl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths = append(paths, "main.yapl")
+	for _, path := range paths {
+		path := path
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			source, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			walked := runBackend(t, string(source), path, false)
+			compiled := runBackend(t, string(source), path, true)
+			if walked != compiled {
+				t.Errorf("interpreter printed:\n%s\nVM printed:\n%s", walked, compiled)
+			}
+		})
+	}
+}
+
+// TestBackendsPrint runs short programs on both backends and checks what
+// they print, for behaviour the backends could agree on and still get
+// wrong.
+func TestBackendsPrint(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"function equals itself", "fun f() {}\nfun g() {}\nprint f == f;\nprint f == g;", "true\nfalse\n"},
+		{"native equals itself", "print clock == clock;\nprint clock == len;", "true\nfalse\n"},
+		{"class equals itself", "class A {}\nclass B {}\nprint A == A;\nprint A == B;", "true\nfalse\n"},
+		{"instance equals itself", "class A {}\nvar a = A();\nprint a == a;\nprint a == A();\nprint a != a;", "true\nfalse\nfalse\n"},
+	}
+	for _, test := range tests {
+		for _, useVM := range []bool{false, true} {
+			if got := runBackend(t, test.source, test.name, useVM); got != test.want {
+				t.Errorf("%s (vm: %v): printed %q, want %q", test.name, useVM, got, test.want)
+			}
+		}
+	}
+}
+
+// Interpret reports runtime errors to the configured Stdout, not the
+// process's, so an embedder can capture them.
+func TestInterpretWritesErrorsToStdout(t *testing.T) {
+	const source = "print 1;\nprint nil + 1;"
+	files := &token.FileSet{}
+	reporter := &yaplErrors.Reporter{Files: files}
+	statements, err := parse(source, files.Add("", source), reporter, false)
+	if err != nil {
+		t.Fatal(reporter.Errors)
+	}
+	const want = "1\nRuntime error: Operands must be two numbers or two strings.\n[line 2, column 11]\n"
+
+	var walked bytes.Buffer
+	interpreter := interpreter.NewInterpreter()
+	interpreter.Stdout = &walked
+	if interpreter.Interpret(statements) == nil {
+		t.Error("interpreter: no error returned")
+	}
+	var compiled bytes.Buffer
+	machine := vm.NewVM()
+	machine.Stdout = &compiled
+	if machine.Interpret(compiler.NewCompiler(reporter).Compile(statements)) == nil {
+		t.Error("VM: no error returned")
+	}
+	for backend, out := range map[string]string{"interpreter": walked.String(), "VM": compiled.String()} {
+		if out != want {
+			t.Errorf("%s wrote %q, want %q", backend, out, want)
+		}
+	}
+}
+
+// runBackend runs source on the interpreter, or on the VM if useVM is set,
+// and returns what it printed followed by its runtime error, if any.
+func runBackend(t *testing.T, source, path string, useVM bool) string {
+	t.Helper()
+	files := &token.FileSet{}
+	reporter := &yaplErrors.Reporter{Files: files}
+	statements, err := parse(source, files.Add(path, source), reporter, false)
+	if err != nil {
+		t.Fatalf("%s: %v", path, reporter.Errors)
+	}
+
+	var out bytes.Buffer
+	if useVM {
+		function := compiler.NewCompiler(reporter).Compile(statements)
+		if reporter.HadError() {
+			t.Fatalf("%s: %v", path, reporter.Errors)
+		}
+		machine := vm.NewVM()
+		machine.Stdout = &out
+		machine.File = path
+		_, err = machine.Execute(function)
+	} else {
+		interpreter := interpreter.NewInterpreter()
+		interpreter.Stdout = &out
+		interpreter.File = path
+		_, err = interpreter.Execute(statements)
+	}
+	if err != nil {
+		fmt.Fprintln(&out, "Runtime error:", err)
+	}
+	return out.String()
+}
@@ -1,24 +1,44 @@
 package parser
 
 import (
-	"errors"
-
 	"github.com/shubhdevelop/YAPL/Token"
 	"github.com/shubhdevelop/YAPL/YaplErrors"
 	"github.com/shubhdevelop/YAPL/ast"
-	"github.com/shubhdevelop/YAPL/state"
 )
 
-type Parser struct {
-	current int
-	Tokens  []token.Token
-}
+type classType int
 
-func (p *Parser) error(token token.Token, message string) error {
-	yaplErrors.Error(token, message)
-	return errors.New("Error while parsing")
-}
+const (
+	classTypeNone classType = iota
+	classTypeClass
+	classTypeSubclass
+)
 
+type Parser struct {
+	current      int
+	currentClass classType
+	// canInsertBreakOrContinueStatement is true while parsing a loop body
+	canInsertBreakOrContinueStatement bool
+	Tokens                            []token.Token
+	Reporter                          *yaplErrors.Reporter
+	// OptionalFinalSemicolon lets the last statement leave out its closing
+	// semicolon, as at the REPL prompt or in a host's one-line Eval.
+	OptionalFinalSemicolon bool
+	// errors holds every syntax error reported during Parse
+	errors []error
+}
+
+// error reports a syntax error and returns it. Errors the parser can't
+// continue past are panicked with the returned value, which declaration
+// recovers to synchronize and carry on with the next statement.
+func (p *Parser) error(token token.Token, message string) yaplErrors.SyntaxError {
+	err := p.Reporter.Error(token, message)
+	p.errors = append(p.errors, err)
+	return err
+}
+
+// synchronize discards tokens until the start of the next statement: just
+// past a semicolon or at a keyword that begins a statement.
 func (p *Parser) synchronize() {
 	p.advance()
 	for !p.isAtEnd() {
@@ -26,14 +46,8 @@ func (p *Parser) synchronize() {
 			return
 		}
 		switch p.peek().Type {
-		case token.CLASS:
-		case token.FUN:
-		case token.VAR:
-		case token.FOR:
-		case token.IF:
-		case token.WHILE:
-		case token.PRINT:
-		case token.RETURN:
+		case token.CLASS, token.FUN, token.VAR, token.FOR, token.IF, token.WHILE,
+			token.PRINT, token.RETURN, token.BREAK, token.CONTINUE, token.TRY, token.THROW:
 			return
 		}
 		p.advance()
@@ -52,11 +66,25 @@ func (p *Parser) assignment() ast.Expr {
 		if variable, ok := expr.(ast.Variable); ok {
 			name := variable.Name
 			return ast.Assign{
-				Name:  name,
-				Value: value,
+				Name:    name,
+				Value:   value,
+				Binding: ast.NewBinding(),
+			}
+		} else if get, ok := expr.(ast.Get); ok {
+			return ast.Set{
+				Object: get.Object,
+				Name:   get.Name,
+				Value:  value,
+			}
+		} else if index, ok := expr.(ast.Index); ok {
+			return ast.IndexSet{
+				Object:  index.Object,
+				Bracket: index.Bracket,
+				Index:   index.Index,
+				Value:   value,
 			}
 		}
-		yaplErrors.Error(equals, "Invalid assignment target.")
+		p.error(equals, "Invalid assignment target.")
 	}
 	return expr
 }
@@ -119,6 +147,12 @@ func (p *Parser) consume(tokenType token.TokenType, message string) token.Token
 	if p.check(tokenType) {
 		return p.advance()
 	}
+	if tokenType == token.SEMICOLON && p.OptionalFinalSemicolon && p.isAtEnd() {
+		semicolon := p.peek()
+		semicolon.Type = token.SEMICOLON
+		semicolon.Lexeme = ";"
+		return semicolon
+	}
 	panic(p.error(p.peek(), message))
 }
 
@@ -140,6 +174,42 @@ func (p *Parser) isAtEnd() bool {
 	return p.peek().Type == token.EOF
 }
 
+// isMapLiteral reports whether the '{' at the current position opens a map
+// literal rather than a block.
+func (p *Parser) isMapLiteral() bool {
+	return OpensMap(p.Tokens, p.current)
+}
+
+// OpensMap reports whether the '{' at tokens[brace], where a statement
+// starts, opens a map literal rather than a block. A map's first key,
+// however long, is followed by a ':', and a statement never has a ':'
+// outside brackets, so the first ':' or ';' outside brackets, or the
+// matching '}', decides it. An empty '{}' is a block. Comment tokens are
+// skipped, so the formatter can ask about its token stream too.
+func OpensMap(tokens []token.Token, brace int) bool {
+	depth := 0
+	for _, tok := range tokens[brace+1:] {
+		switch tok.Type {
+		case token.LEFT_PAREN, token.LEFT_BRACKET, token.LEFT_BRACE:
+			depth++
+		case token.RIGHT_PAREN, token.RIGHT_BRACKET, token.RIGHT_BRACE:
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case token.COLON:
+			if depth == 0 {
+				return true
+			}
+		case token.SEMICOLON, token.EOF:
+			if depth == 0 {
+				return false
+			}
+		}
+	}
+	return false
+}
+
 func (p *Parser) peek() token.Token {
 	return p.Tokens[p.current]
 }
@@ -199,7 +269,54 @@ func (p *Parser) unary() ast.Expr {
 			Right:    right,
 		}
 	}
-	return p.primary()
+	return p.call()
+}
+
+func (p *Parser) call() ast.Expr {
+	expr := p.primary()
+	for {
+		if p.match(token.LEFT_PAREN) {
+			expr = p.finishCall(expr)
+		} else if p.match(token.DOT) {
+			name := p.consume(token.IDENTIFIER, "Expect property name after '.'.")
+			expr = ast.Get{
+				Object: expr,
+				Name:   name,
+			}
+		} else if p.match(token.LEFT_BRACKET) {
+			index := p.expression()
+			bracket := p.consume(token.RIGHT_BRACKET, "Expect ']' after index.")
+			expr = ast.Index{
+				Object:  expr,
+				Bracket: bracket,
+				Index:   index,
+			}
+		} else {
+			break
+		}
+	}
+	return expr
+}
+
+func (p *Parser) finishCall(callee ast.Expr) ast.Expr {
+	arguments := []ast.Expr{}
+	if !p.check(token.RIGHT_PAREN) {
+		for {
+			if len(arguments) >= 255 {
+				p.error(p.peek(), "Can't have more than 255 arguments.")
+			}
+			arguments = append(arguments, p.expression())
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+	}
+	paren := p.consume(token.RIGHT_PAREN, "Expect ')' after arguments.")
+	return ast.Call{
+		Callee:    callee,
+		Paren:     paren,
+		Arguments: arguments,
+	}
 }
 
 func (p *Parser) primary() ast.Expr {
@@ -214,11 +331,70 @@ func (p *Parser) primary() ast.Expr {
 		return ast.Literal{Value: p.previous().Literal}
 	case p.match(token.STRING):
 		return ast.Literal{Value: p.previous().Literal}
+	case p.match(token.SUPER):
+		keyword := p.previous()
+		if p.currentClass == classTypeNone {
+			p.error(keyword, "Can't use 'super' outside of a class.")
+		} else if p.currentClass != classTypeSubclass {
+			p.error(keyword, "Can't use 'super' in a class with no superclass.")
+		}
+		p.consume(token.DOT, "Expect '.' after 'super'.")
+		method := p.consume(token.IDENTIFIER, "Expect superclass method name.")
+		return ast.Super{
+			Keyword: keyword,
+			Method:  method,
+			Binding: ast.NewBinding(),
+		}
+	case p.match(token.THIS):
+		if p.currentClass == classTypeNone {
+			p.error(p.previous(), "Can't use 'this' outside of a class.")
+		}
+		return ast.This{
+			Keyword: p.previous(),
+			Binding: ast.NewBinding(),
+		}
 	case p.match(token.IDENTIFIER):
 		return ast.Variable{
-			Name: p.previous(),
+			Name:    p.previous(),
+			Binding: ast.NewBinding(),
 		}
 
+	case p.match(token.LEFT_BRACKET):
+		bracket := p.previous()
+		elements := []ast.Expr{}
+		if !p.check(token.RIGHT_BRACKET) {
+			for {
+				elements = append(elements, p.expression())
+				if !p.match(token.COMMA) {
+					break
+				}
+			}
+		}
+		p.consume(token.RIGHT_BRACKET, "Expect ']' after list elements.")
+		return ast.List{
+			Bracket:  bracket,
+			Elements: elements,
+		}
+	case p.match(token.LEFT_BRACE):
+		brace := p.previous()
+		keys := []ast.Expr{}
+		values := []ast.Expr{}
+		if !p.check(token.RIGHT_BRACE) {
+			for {
+				keys = append(keys, p.expression())
+				p.consume(token.COLON, "Expect ':' after map key.")
+				values = append(values, p.expression())
+				if !p.match(token.COMMA) {
+					break
+				}
+			}
+		}
+		p.consume(token.RIGHT_BRACE, "Expect '}' after map entries.")
+		return ast.Map{
+			Brace:  brace,
+			Keys:   keys,
+			Values: values,
+		}
 	case p.match(token.LEFT_PAREN):
 		expr := p.expression()
 		p.consume(token.RIGHT_PAREN, "Expect ')' after expression.")
@@ -228,31 +404,117 @@ func (p *Parser) primary() ast.Expr {
 	}
 }
 
-func (p *Parser) Parse() []ast.Stmt {
+// Parse parses every declaration in Tokens. It doesn't stop at the first
+// syntax error: each one is reported, the parser skips to the next
+// statement and keeps going, and all of them are returned together. The
+// statements are only fit to run when there are no errors.
+func (p *Parser) Parse() ([]ast.Stmt, []error) {
 	statements := []ast.Stmt{}
+	if len(p.Tokens) == 0 {
+		return statements, nil
+	}
 	for !p.isAtEnd() {
-		statements = append(statements, p.declaration())
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
+		}
 	}
-	return statements
+	return statements, p.errors
 }
 
-func (p *Parser) declaration() ast.Stmt {
+// declaration parses one declaration or statement. If it has a syntax error
+// it returns nil once the parser has synchronized.
+func (p *Parser) declaration() (stmt ast.Stmt) {
 	defer func() {
 		if r := recover(); r != nil {
-			if _, ok := r.(yaplErrors.RuntimeError); ok {
+			if _, ok := r.(yaplErrors.SyntaxError); ok {
 				p.synchronize()
+				stmt = nil
 			} else {
 				panic(r) // rethrow if it's not a ParseError
 			}
 		}
 	}()
 
+	if p.match(token.CLASS) {
+		return p.classDeclaration()
+	}
+	if p.match(token.FUN) {
+		return p.function("function")
+	}
 	if p.match(token.VAR) {
 		return p.varDeclaration()
 	}
 	return p.statement()
 }
 
+func (p *Parser) classDeclaration() ast.Stmt {
+	name := p.consume(token.IDENTIFIER, "Expect class name.")
+
+	enclosingClass := p.currentClass
+	p.currentClass = classTypeClass
+	defer func() {
+		p.currentClass = enclosingClass
+	}()
+
+	var superclass *ast.Variable
+	if p.match(token.LESS) {
+		p.consume(token.IDENTIFIER, "Expect superclass name.")
+		superclass = &ast.Variable{
+			Name:    p.previous(),
+			Binding: ast.NewBinding(),
+		}
+		if superclass.Name.Lexeme == name.Lexeme {
+			p.error(superclass.Name, "A class can't inherit from itself.")
+		}
+		p.currentClass = classTypeSubclass
+	}
+
+	p.consume(token.LEFT_BRACE, "Expect '{' before class body.")
+
+	methods := []ast.FunctionStmt{}
+	for !p.check(token.RIGHT_BRACE) && !p.isAtEnd() {
+		methods = append(methods, p.function("method"))
+	}
+	p.consume(token.RIGHT_BRACE, "Expect '}' after class body.")
+	return ast.ClassStmt{
+		Name:       name,
+		Superclass: superclass,
+		Methods:    methods,
+	}
+}
+
+func (p *Parser) function(kind string) ast.FunctionStmt {
+	name := p.consume(token.IDENTIFIER, "Expect "+kind+" name.")
+	p.consume(token.LEFT_PAREN, "Expect '(' after "+kind+" name.")
+	parameters := []token.Token{}
+	if !p.check(token.RIGHT_PAREN) {
+		for {
+			if len(parameters) >= 255 {
+				p.error(p.peek(), "Can't have more than 255 parameters.")
+			}
+			parameters = append(parameters, p.consume(token.IDENTIFIER, "Expect parameter name."))
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+	}
+	p.consume(token.RIGHT_PAREN, "Expect ')' after parameters.")
+
+	p.consume(token.LEFT_BRACE, "Expect '{' before "+kind+" body.")
+	// break and continue must not escape a function body into an enclosing loop
+	enclosingLoop := p.canInsertBreakOrContinueStatement
+	p.canInsertBreakOrContinueStatement = false
+	defer func() {
+		p.canInsertBreakOrContinueStatement = enclosingLoop
+	}()
+	body := p.block()
+	return ast.FunctionStmt{
+		Name:   name,
+		Params: parameters,
+		Body:   body,
+	}
+}
+
 func (p *Parser) varDeclaration() ast.Stmt {
 	name := p.consume(token.IDENTIFIER, "Expected variable name")
 	var initializer ast.Expr = nil
@@ -273,6 +535,9 @@ func (p *Parser) statement() ast.Stmt {
 	if p.match(token.PRINT) {
 		return p.printStatement()
 	}
+	if p.match(token.RETURN) {
+		return p.returnStatement()
+	}
 	if p.match(token.CONTINUE) {
 		return p.continueStatement()
 	}
@@ -282,7 +547,14 @@ func (p *Parser) statement() ast.Stmt {
 	if p.match(token.WHILE) {
 		return p.whileStatement()
 	}
-	if p.match(token.LEFT_BRACE) {
+	if p.match(token.TRY) {
+		return p.tryStatement()
+	}
+	if p.match(token.THROW) {
+		return p.throwStatement()
+	}
+	if p.check(token.LEFT_BRACE) && !p.isMapLiteral() {
+		p.advance()
 		return ast.BlockStmt{
 			Statement: p.block(),
 		}
@@ -291,6 +563,7 @@ func (p *Parser) statement() ast.Stmt {
 }
 
 func (p *Parser) forStatement() ast.Stmt {
+	keyword := p.previous()
 	p.consume(token.LEFT_PAREN, "Expect '(' after 'for'.")
 	var initializer ast.Stmt
 	if p.match(token.SEMICOLON) {
@@ -313,27 +586,18 @@ func (p *Parser) forStatement() ast.Stmt {
 	}
 	p.consume(token.RIGHT_PAREN, "Expect ')' after for clauses.")
 
-	state.CanInsertBreakOrContinueStatement = true
-	body := p.statement()
-	state.CanInsertBreakOrContinueStatement = false
-
-	if increment != nil {
-		body = ast.BlockStmt{
-			Statement: []ast.Stmt{
-				body,
-				ast.ExpressionStmt{
-					Expression: increment,
-				},
-			},
-		}
-	}
+	body := p.loopBody()
 
 	if condition == nil {
 		condition = ast.Literal{Value: true}
 	}
+	// The increment is kept apart from the body so that continue, which
+	// leaves the body early, still runs it.
 	body = ast.WhileStmt{
+		Keyword:   keyword,
 		Condition: condition,
 		Body:      body,
+		Increment: increment,
 	}
 
 	if initializer != nil {
@@ -349,6 +613,7 @@ func (p *Parser) forStatement() ast.Stmt {
 }
 
 func (p *Parser) ifStatement() ast.Stmt {
+	keyword := p.previous()
 	p.consume(token.LEFT_PAREN, "Expect '(' after 'if'.")
 	condition := p.expression()
 
@@ -359,6 +624,7 @@ func (p *Parser) ifStatement() ast.Stmt {
 		elseBranch = p.statement()
 	}
 	return ast.IfStmt{
+		Keyword:    keyword,
 		Condition:  condition,
 		ThenBranch: thenBranch,
 		ElseBranch: elseBranch,
@@ -366,50 +632,120 @@ func (p *Parser) ifStatement() ast.Stmt {
 }
 
 func (p *Parser) whileStatement() ast.Stmt {
+	keyword := p.previous()
 	p.consume(token.LEFT_PAREN, "Expect '(' after 'while'.")
 	condition := p.expression()
 	p.consume(token.RIGHT_PAREN, "Expect ')' after condition.")
 
-	state.CanInsertBreakOrContinueStatement = true
-	body := p.statement()
-	state.CanInsertBreakOrContinueStatement = false
+	body := p.loopBody()
 	return ast.WhileStmt{
+		Keyword:   keyword,
 		Condition: condition,
 		Body:      body,
 	}
 
 }
+
+// loopBody parses the body of a while or for loop, where break and continue
+// are allowed. The previous setting is restored afterwards so a nested loop
+// doesn't forbid them in the rest of the enclosing loop.
+func (p *Parser) loopBody() ast.Stmt {
+	enclosingLoop := p.canInsertBreakOrContinueStatement
+	p.canInsertBreakOrContinueStatement = true
+	defer func() {
+		// restored even when a syntax error unwinds out of the body
+		p.canInsertBreakOrContinueStatement = enclosingLoop
+	}()
+	return p.statement()
+}
+
 func (p *Parser) continueStatement() ast.Stmt {
-	if !state.CanInsertBreakOrContinueStatement {
+	keyword := p.previous()
+	if !p.canInsertBreakOrContinueStatement {
 		p.error(p.peek(), "continueStatemen can only exist inside valid iterator")
 
 	}
 	p.consume(token.SEMICOLON, "Expect ';' after value.")
-	return ast.ContinueStmt{}
+	return ast.ContinueStmt{Keyword: keyword}
 }
 
 func (p *Parser) breakStatement() ast.Stmt {
-	if !state.CanInsertBreakOrContinueStatement {
+	keyword := p.previous()
+	if !p.canInsertBreakOrContinueStatement {
 		p.error(p.peek(), "breakStatement can only exist inside valid iterator")
 
 	}
 	p.consume(token.SEMICOLON, "Expect ';' after value.")
-	return ast.BreakStmt{}
+	return ast.BreakStmt{Keyword: keyword}
+}
+
+func (p *Parser) returnStatement() ast.Stmt {
+	keyword := p.previous()
+	var value ast.Expr
+	if !p.check(token.SEMICOLON) {
+		value = p.expression()
+	}
+	p.consume(token.SEMICOLON, "Expect ';' after return value.")
+	return ast.ReturnStmt{
+		Keyword: keyword,
+		Value:   value,
+	}
+}
+
+// tryStatement parses try { ... } followed by a catch clause, a finally
+// clause or both. FinallyBody is left nil when there is no finally clause.
+func (p *Parser) tryStatement() ast.Stmt {
+	keyword := p.previous()
+	p.consume(token.LEFT_BRACE, "Expect '{' after 'try'.")
+	stmt := ast.TryStmt{
+		Keyword: keyword,
+		Body:    p.block(),
+	}
+	if p.match(token.CATCH) {
+		p.consume(token.LEFT_PAREN, "Expect '(' after 'catch'.")
+		name := p.consume(token.IDENTIFIER, "Expect exception variable name.")
+		p.consume(token.RIGHT_PAREN, "Expect ')' after exception variable name.")
+		p.consume(token.LEFT_BRACE, "Expect '{' before catch body.")
+		stmt.CatchName = &name
+		stmt.CatchBody = p.block()
+	}
+	if p.match(token.FINALLY) {
+		p.consume(token.LEFT_BRACE, "Expect '{' after 'finally'.")
+		stmt.FinallyBody = p.block()
+	}
+	if stmt.CatchName == nil && stmt.FinallyBody == nil {
+		p.error(p.peek(), "Expect 'catch' or 'finally' after try block.")
+	}
+	return stmt
+}
+
+func (p *Parser) throwStatement() ast.Stmt {
+	keyword := p.previous()
+	value := p.expression()
+	p.consume(token.SEMICOLON, "Expect ';' after thrown value.")
+	return ast.ThrowStmt{
+		Keyword: keyword,
+		Value:   value,
+	}
 }
 
 func (p *Parser) block() []ast.Stmt {
 	statements := []ast.Stmt{}
 	for !p.check(token.RIGHT_BRACE) && !p.isAtEnd() {
-		statements = append(statements, p.declaration())
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
+		}
 	}
 	p.consume(token.RIGHT_BRACE, "Expect '}' after block.")
 	return statements
 }
 
 func (p *Parser) printStatement() ast.Stmt {
+	keyword := p.previous()
 	value := p.expression()
 	p.consume(token.SEMICOLON, "Expect ';' after value.")
 	return ast.PrintStmt{
+		Keyword:    keyword,
 		Expression: value,
 	}
 }
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/shubhdevelop/YAPL/Scanner"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/ast"
+)
+
+func TestInvalidAssignmentTargetIsAParseError(t *testing.T) {
+	reporter := &yaplErrors.Reporter{}
+	scanner := scanner.Scanner{Source: "1 = a;\nprint 2;", Reporter: reporter}
+	tokens, _ := scanner.ScanTokens()
+	parser := Parser{Tokens: tokens, Reporter: reporter}
+	statements, errors := parser.Parse()
+	if len(errors) != 1 || errors[0].Error() != "[line 1] Error at '=': Invalid assignment target." {
+		t.Fatalf("got errors %v", errors)
+	}
+	// The statement after it still parses.
+	if len(statements) != 2 {
+		t.Fatalf("got %d statements, want 2", len(statements))
+	}
+}
+
+func TestBraceAtStatementStart(t *testing.T) {
+	tests := []struct {
+		source string
+		isMap  bool
+	}{
+		{`{"k": 1};`, true},
+		{`{k: 1};`, true},
+		{`{1 + 2: 3};`, true},
+		{`{f(a, b): [1, 2]};`, true},
+		{`{}`, false},
+		{`{ print 1; }`, false},
+		{`{ print {"k": 1}; }`, false},
+		{`{ for (var i = 0; i < 1; i = i + 1) print i; }`, false},
+		{`{ if (a) { b(); } }`, false},
+	}
+	for _, test := range tests {
+		reporter := &yaplErrors.Reporter{}
+		scanner := scanner.Scanner{Source: test.source, Reporter: reporter}
+		tokens, _ := scanner.ScanTokens()
+		parser := Parser{Tokens: tokens, Reporter: reporter}
+		statements, errors := parser.Parse()
+		if len(errors) > 0 || len(statements) != 1 {
+			t.Errorf("%s: got %d statements and errors %v", test.source, len(statements), errors)
+			continue
+		}
+		_, isBlock := statements[0].(ast.BlockStmt)
+		if isBlock == test.isMap {
+			t.Errorf("%s: parsed as %T", test.source, statements[0])
+		}
+	}
+}
@@ -113,19 +113,33 @@ func main() {
 		"Literal  : interface{} value",
 		"Logical  : Expr left, token.Token operator, Expr right",
 		"Unary    : token.Token operator, Expr right",
-		"Variable : token.Token name",
-		"Assign   : token.Token name, Expr value",
+		"Variable : token.Token name, *Binding binding",
+		"Assign   : token.Token name, Expr value, *Binding binding",
+		"Call     : Expr callee, token.Token paren, []Expr arguments",
+		"Get      : Expr object, token.Token name",
+		"Set      : Expr object, token.Token name, Expr value",
+		"Super    : token.Token keyword, token.Token method, *Binding binding",
+		"This     : token.Token keyword, *Binding binding",
+		"List     : token.Token bracket, []Expr elements",
+		"Map      : token.Token brace, []Expr keys, []Expr values",
+		"Index    : Expr object, token.Token bracket, Expr index",
+		"IndexSet : Expr object, token.Token bracket, Expr index, Expr value",
 	}, []string{"github.com/shubhdevelop/YAPL/Token"})
 
 	defineAst(outputDir, "Stmt", []string{
 		"BlockStmt      : []Stmt statement",
 		"ExpressionStmt : Expr expression",
-		"IfStmt : Expr condition, Stmt thenBranch," +
+		"IfStmt : token.Token keyword, Expr condition, Stmt thenBranch," +
 			" Stmt elseBranch",
-		"PrintStmt      : Expr expression",
+		"PrintStmt      : token.Token keyword, Expr expression",
 		"VarStmt : token.Token name, Expr initializer",
-		"WhileStmt: Expr condition, Stmt body",
-		"BreakStmt: ",
-		"ContinueStmt: ",
+		"WhileStmt: token.Token keyword, Expr condition, Stmt body, Expr increment",
+		"BreakStmt: token.Token keyword",
+		"ContinueStmt: token.Token keyword",
+		"FunctionStmt: token.Token name, []token.Token params, []Stmt body",
+		"ReturnStmt: token.Token keyword, Expr value",
+		"ClassStmt: token.Token name, *Variable superclass, []FunctionStmt methods",
+		"TryStmt: token.Token keyword, []Stmt body, *token.Token catchName, []Stmt catchBody, []Stmt finallyBody",
+		"ThrowStmt: token.Token keyword, Expr value",
 	}, []string{"github.com/shubhdevelop/YAPL/Token"})
 }
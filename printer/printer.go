@@ -2,15 +2,20 @@ package printer
 
 import (
 	"fmt"
+	"github.com/shubhdevelop/YAPL/Token"
 	"github.com/shubhdevelop/YAPL/ast"
+	"strconv"
 	"strings"
 )
 
-// AstPrinter implements ast.Visitor
+// AstPrinter prints syntax trees as S-expressions. Expressions print on one
+// line; statements that contain other statements print each of them on its
+// own line, indented.
 type AstPrinter struct{}
 
-// Ensure AstPrinter implements ast.Visitor at compile time
+// Ensure AstPrinter implements both visitors at compile time
 var _ ast.ExprVisitor = (*AstPrinter)(nil)
+var _ ast.StmtVisitor = (*AstPrinter)(nil)
 
 // Print converts an expression to its string representation
 func (p *AstPrinter) Print(expr ast.Expr) string {
@@ -36,8 +41,12 @@ func (p *AstPrinter) VisitGroupingExpr(expr ast.Grouping) interface{} {
 
 // VisitLiteralExpr handles literal expressions
 func (p *AstPrinter) VisitLiteralExpr(expr ast.Literal) interface{} {
-	if expr.Value == nil {
+	switch value := expr.Value.(type) {
+	case nil:
 		return "nil"
+	case string:
+		// Quoted so strings can't be mistaken for names
+		return strconv.Quote(value)
 	}
 	return fmt.Sprintf("%v", expr.Value)
 }
@@ -47,28 +56,105 @@ func (p *AstPrinter) VisitUnaryExpr(expr ast.Unary) interface{} {
 	return p.parenthesize(expr.Operator.Lexeme, expr.Right)
 }
 
-// parenthesize wraps expressions in parentheses with an operator/name
-func (p *AstPrinter) parenthesize(name string, exprs ...ast.Expr) string {
+// VisitLogicalExpr handles and/or
+func (p *AstPrinter) VisitLogicalExpr(expr ast.Logical) interface{} {
+	return p.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
+}
+
+// VisitVariableExpr prints the variable's name
+func (p *AstPrinter) VisitVariableExpr(expr ast.Variable) interface{} {
+	return expr.Name.Lexeme
+}
+
+// VisitAssignExpr handles assignment to a variable
+func (p *AstPrinter) VisitAssignExpr(expr ast.Assign) interface{} {
+	return p.parenthesize("=", expr.Name, expr.Value)
+}
+
+// VisitCallExpr handles calls
+func (p *AstPrinter) VisitCallExpr(expr ast.Call) interface{} {
+	parts := []interface{}{expr.Callee}
+	for _, argument := range expr.Arguments {
+		parts = append(parts, argument)
+	}
+	return p.parenthesize("call", parts...)
+}
+
+// VisitGetExpr handles property access
+func (p *AstPrinter) VisitGetExpr(expr ast.Get) interface{} {
+	return p.parenthesize(".", expr.Object, expr.Name)
+}
+
+// VisitSetExpr handles assignment to a property
+func (p *AstPrinter) VisitSetExpr(expr ast.Set) interface{} {
+	return p.parenthesize(".=", expr.Object, expr.Name, expr.Value)
+}
+
+// VisitSuperExpr handles super.method
+func (p *AstPrinter) VisitSuperExpr(expr ast.Super) interface{} {
+	return p.parenthesize("super", expr.Method)
+}
+
+// VisitThisExpr prints this
+func (p *AstPrinter) VisitThisExpr(expr ast.This) interface{} {
+	return "this"
+}
+
+// VisitListExpr handles list literals
+func (p *AstPrinter) VisitListExpr(expr ast.List) interface{} {
+	parts := []interface{}{}
+	for _, element := range expr.Elements {
+		parts = append(parts, element)
+	}
+	return p.parenthesize("list", parts...)
+}
+
+// VisitMapExpr handles map literals, printing keys and values in pairs
+func (p *AstPrinter) VisitMapExpr(expr ast.Map) interface{} {
+	parts := []interface{}{}
+	for idx, key := range expr.Keys {
+		parts = append(parts, key, expr.Values[idx])
+	}
+	return p.parenthesize("map", parts...)
+}
+
+// VisitIndexExpr handles subscripts
+func (p *AstPrinter) VisitIndexExpr(expr ast.Index) interface{} {
+	return p.parenthesize("[]", expr.Object, expr.Index)
+}
+
+// VisitIndexSetExpr handles assignment to a subscript
+func (p *AstPrinter) VisitIndexSetExpr(expr ast.IndexSet) interface{} {
+	return p.parenthesize("[]=", expr.Object, expr.Index, expr.Value)
+}
+
+// parenthesize wraps parts in parentheses after an operator/name. A part
+// is an expression, which is printed recursively, a token, which is
+// printed as its lexeme, or a string, which is printed as it is.
+func (p *AstPrinter) parenthesize(name string, parts ...interface{}) string {
+	return p.open(name, parts...) + ")"
+}
+
+// open is parenthesize without the closing parenthesis.
+func (p *AstPrinter) open(name string, parts ...interface{}) string {
 	var builder strings.Builder
 
 	builder.WriteString("(")
 	builder.WriteString(name)
 
-	for _, expr := range exprs {
-		if expr == nil {
-			builder.WriteString(" nil")
-			continue
-		}
-
+	for _, part := range parts {
 		builder.WriteString(" ")
-		result := expr.Accept(p)
-		if str, ok := result.(string); ok {
-			builder.WriteString(str)
-		} else {
-			builder.WriteString(fmt.Sprintf("%v", result))
+		switch part := part.(type) {
+		case nil:
+			builder.WriteString("nil")
+		case token.Token:
+			builder.WriteString(part.Lexeme)
+		case ast.Expr:
+			builder.WriteString(p.Print(part))
+		default:
+			builder.WriteString(fmt.Sprintf("%v", part))
 		}
 	}
 
-	builder.WriteString(")")
 	return builder.String()
 }
@@ -0,0 +1,155 @@
+package printer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shubhdevelop/YAPL/ast"
+)
+
+// PrintStmt converts a statement to its string representation
+func (p *AstPrinter) PrintStmt(stmt ast.Stmt) string {
+	if stmt == nil {
+		return "nil"
+	}
+	result := stmt.Accept(p)
+	if str, ok := result.(string); ok {
+		return str
+	}
+	return fmt.Sprintf("%v", result)
+}
+
+// PrintProgram prints each statement of a program on its own line
+func (p *AstPrinter) PrintProgram(stmts []ast.Stmt) string {
+	lines := make([]string, len(stmts))
+	for idx, stmt := range stmts {
+		lines[idx] = p.PrintStmt(stmt)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// VisitBlockStmtStmt handles blocks
+func (p *AstPrinter) VisitBlockStmtStmt(stmt ast.BlockStmt) interface{} {
+	return p.nest(p.open("block"), p.statements(stmt.Statement)...)
+}
+
+// VisitExpressionStmtStmt handles expression statements
+func (p *AstPrinter) VisitExpressionStmtStmt(stmt ast.ExpressionStmt) interface{} {
+	return p.parenthesize(";", stmt.Expression)
+}
+
+// VisitIfStmtStmt handles if, with the else branch last when there is one
+func (p *AstPrinter) VisitIfStmtStmt(stmt ast.IfStmt) interface{} {
+	branches := []string{p.PrintStmt(stmt.ThenBranch)}
+	if stmt.ElseBranch != nil {
+		branches = append(branches, p.PrintStmt(stmt.ElseBranch))
+	}
+	return p.nest(p.open("if", stmt.Condition), branches...)
+}
+
+// VisitPrintStmtStmt handles print statements
+func (p *AstPrinter) VisitPrintStmtStmt(stmt ast.PrintStmt) interface{} {
+	return p.parenthesize("print", stmt.Expression)
+}
+
+// VisitVarStmtStmt handles variable declarations
+func (p *AstPrinter) VisitVarStmtStmt(stmt ast.VarStmt) interface{} {
+	if stmt.Initializer == nil {
+		return p.parenthesize("var", stmt.Name)
+	}
+	return p.parenthesize("var", stmt.Name, "=", stmt.Initializer)
+}
+
+// VisitWhileStmtStmt handles while loops, and for loops, which the parser
+// turns into them, with the increment last
+func (p *AstPrinter) VisitWhileStmtStmt(stmt ast.WhileStmt) interface{} {
+	children := []string{p.PrintStmt(stmt.Body)}
+	if stmt.Increment != nil {
+		children = append(children, p.parenthesize("increment", stmt.Increment))
+	}
+	return p.nest(p.open("while", stmt.Condition), children...)
+}
+
+// VisitBreakStmtStmt handles break
+func (p *AstPrinter) VisitBreakStmtStmt(stmt ast.BreakStmt) interface{} {
+	return "(break)"
+}
+
+// VisitContinueStmtStmt handles continue
+func (p *AstPrinter) VisitContinueStmtStmt(stmt ast.ContinueStmt) interface{} {
+	return "(continue)"
+}
+
+// VisitFunctionStmtStmt handles function declarations
+func (p *AstPrinter) VisitFunctionStmtStmt(stmt ast.FunctionStmt) interface{} {
+	return p.function("fun", stmt)
+}
+
+// VisitReturnStmtStmt handles return, with or without a value
+func (p *AstPrinter) VisitReturnStmtStmt(stmt ast.ReturnStmt) interface{} {
+	if stmt.Value == nil {
+		return "(return)"
+	}
+	return p.parenthesize("return", stmt.Value)
+}
+
+// VisitClassStmtStmt handles class declarations
+func (p *AstPrinter) VisitClassStmtStmt(stmt ast.ClassStmt) interface{} {
+	head := p.open("class", stmt.Name)
+	if stmt.Superclass != nil {
+		head = p.open("class", stmt.Name, "<", *stmt.Superclass)
+	}
+	methods := make([]string, len(stmt.Methods))
+	for idx, method := range stmt.Methods {
+		methods[idx] = p.function("method", method)
+	}
+	return p.nest(head, methods...)
+}
+
+// VisitTryStmtStmt handles try, printing each clause as its own list
+func (p *AstPrinter) VisitTryStmtStmt(stmt ast.TryStmt) interface{} {
+	clauses := []string{p.nest(p.open("body"), p.statements(stmt.Body)...)}
+	if stmt.CatchName != nil {
+		clauses = append(clauses, p.nest(p.open("catch", *stmt.CatchName), p.statements(stmt.CatchBody)...))
+	}
+	if stmt.FinallyBody != nil {
+		clauses = append(clauses, p.nest(p.open("finally"), p.statements(stmt.FinallyBody)...))
+	}
+	return p.nest(p.open("try"), clauses...)
+}
+
+// VisitThrowStmtStmt handles throw
+func (p *AstPrinter) VisitThrowStmtStmt(stmt ast.ThrowStmt) interface{} {
+	return p.parenthesize("throw", stmt.Value)
+}
+
+// function prints a function or method with its parameters and body
+func (p *AstPrinter) function(kind string, stmt ast.FunctionStmt) string {
+	params := make([]string, len(stmt.Params))
+	for idx, param := range stmt.Params {
+		params[idx] = param.Lexeme
+	}
+	head := p.open(kind, stmt.Name, "("+strings.Join(params, " ")+")")
+	return p.nest(head, p.statements(stmt.Body)...)
+}
+
+func (p *AstPrinter) statements(stmts []ast.Stmt) []string {
+	printed := make([]string, len(stmts))
+	for idx, stmt := range stmts {
+		printed[idx] = p.PrintStmt(stmt)
+	}
+	return printed
+}
+
+// nest closes head, an unclosed list from open, after children, each on
+// its own line and indented two spaces more than head.
+func (p *AstPrinter) nest(head string, children ...string) string {
+	var builder strings.Builder
+	builder.WriteString(head)
+	for _, child := range children {
+		builder.WriteString("\n  ")
+		builder.WriteString(strings.ReplaceAll(child, "\n", "\n  "))
+	}
+	builder.WriteString(")")
+	return builder.String()
+}
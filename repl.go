@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	interpreter "github.com/shubhdevelop/YAPL/Interpreter"
+	"github.com/shubhdevelop/YAPL/Scanner"
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/compiler"
+	"github.com/shubhdevelop/YAPL/frontend"
+	"github.com/shubhdevelop/YAPL/vm"
+)
+
+// session is one run of the REPL. Its interpreter, or its VM with --vm,
+// lives as long as the session, so variables, functions and classes
+// declared on one line can be used on the next.
+type session struct {
+	files       *token.FileSet
+	interpreter *interpreter.Interpreter
+	machine     *vm.VM
+}
+
+func newSession() *session {
+	s := &session{}
+	s.reset()
+	return s
+}
+
+// reset throws away everything the session has defined.
+func (s *session) reset() {
+	s.files = &token.FileSet{}
+	s.interpreter, s.machine = nil, nil
+	if *useVM {
+		s.machine = vm.NewVM()
+	} else {
+		s.interpreter = interpreter.NewInterpreter()
+	}
+}
+
+// eval runs one input. file names it in errors and traces and is empty for
+// input typed at the prompt. Errors are reported and the session carries
+// on; the value of a trailing expression statement is echoed unless it is
+// nil.
+func (s *session) eval(source, file string) {
+	if run, ok := s.compile(source, file); ok {
+		run()
+	}
+}
+
+// compile scans, parses and resolves source, and with --vm compiles it to
+// bytecode, reporting any errors. It returns a function that runs the
+// result the way eval does, so :time can measure just that part.
+func (s *session) compile(source, file string) (func(), bool) {
+	reporter := &yaplErrors.Reporter{Output: os.Stderr, Files: s.files}
+	statements, err := parse(source, s.files.Add(file, source), reporter, true)
+	if err != nil {
+		return nil, false
+	}
+	if s.machine != nil {
+		function := compiler.NewCompiler(reporter).CompileREPL(statements)
+		if reporter.HadError() {
+			return nil, false
+		}
+		return func() {
+			s.machine.File = file
+			value, err := s.machine.Execute(function)
+			if err != nil {
+				fmt.Println("Runtime error:", yaplErrors.FormatError(err, s.files))
+			} else if !value.IsNil() {
+				fmt.Println(value)
+			}
+		}, true
+	}
+	return func() {
+		s.interpreter.File = file
+		value, err := s.interpreter.Execute(statements)
+		if err != nil {
+			fmt.Println("Runtime error:", yaplErrors.FormatError(err, s.files))
+		} else if value != nil {
+			fmt.Println(interpreter.Stringify(value))
+		}
+	}, true
+}
+
+// incomplete reports whether source stops partway through a statement, so
+// the REPL should read another line before running it. That is the case
+// when it ends inside a string or when every parse error is at the end of
+// the input, as it is for an unclosed bracket or a trailing operator.
+func incomplete(source string) bool {
+	result := frontend.Parse(source, frontend.Options{OptionalFinalSemicolon: true, NoResolve: true})
+	for _, err := range result.Errors {
+		if syntaxErr, ok := err.(yaplErrors.SyntaxError); ok && syntaxErr.Message == scanner.UnterminatedString {
+			return true
+		}
+	}
+	if len(result.Errors) == 0 {
+		return false
+	}
+	for _, err := range result.Errors {
+		if syntaxErr, ok := err.(yaplErrors.SyntaxError); !ok || syntaxErr.Where != " at end" {
+			return false
+		}
+	}
+	return true
+}
+
+// completions returns the words that start with word, for tab completion:
+// keywords and the session's globals, or commands if word starts with ':'.
+func (s *session) completions(word string) []string {
+	var names []string
+	if strings.HasPrefix(word, ":") {
+		for _, command := range replCommands {
+			names = append(names, ":"+command.name)
+		}
+	} else {
+		for keyword := range scanner.KeywordMap {
+			names = append(names, keyword)
+		}
+		if s.machine != nil {
+			for name := range s.machine.Globals() {
+				names = append(names, name)
+			}
+		} else {
+			for name := range s.interpreter.Globals.Values {
+				names = append(names, name)
+			}
+		}
+	}
+
+	var matches []string
+	for _, name := range names {
+		if strings.HasPrefix(name, word) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+func runPrompt() {
+	session := newSession()
+	editor := newLineEditor(session.completions)
+	// pending holds the lines of a statement that isn't complete yet.
+	var pending strings.Builder
+
+	for {
+		prompt := ">> "
+		if pending.Len() > 0 {
+			prompt = ".. "
+		}
+		line, err := editor.readLine(prompt)
+		if errors.Is(err, errInterrupted) {
+			pending.Reset()
+			continue
+		}
+		if err != nil {
+			// End of input, e.g. Ctrl-D
+			fmt.Println()
+			if pending.Len() == 0 {
+				return
+			}
+			// Run the unfinished statement anyway so its errors are
+			// reported rather than lost.
+			session.eval(pending.String(), "")
+			pending.Reset()
+			continue
+		}
+		if pending.Len() == 0 {
+			switch trimmed := strings.TrimSpace(line); {
+			case trimmed == "":
+				continue
+			case trimmed == "clear":
+				fmt.Print("\033[H\033[2J")
+				continue
+			case trimmed == "exit":
+				return
+			case strings.HasPrefix(trimmed, ":"):
+				session.command(trimmed)
+				continue
+			}
+		}
+		pending.WriteString(line + "\n")
+		if incomplete(pending.String()) {
+			continue
+		}
+		session.eval(pending.String(), "")
+		pending.Reset()
+	}
+}
@@ -0,0 +1,114 @@
+package resolver
+
+import (
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/ast"
+)
+
+// DeclarationKind says what a Declaration declares.
+type DeclarationKind int
+
+const (
+	VariableDeclaration DeclarationKind = iota
+	FunctionDeclaration
+	ClassDeclaration
+	MethodDeclaration
+	ParameterDeclaration
+	// CatchDeclaration is the exception variable of a catch clause.
+	CatchDeclaration
+)
+
+// Declaration is a name the program declares, as the resolver saw it.
+// Tools such as the linter and the language server read them, with the
+// Uses that refer to them, instead of keeping scopes of their own.
+type Declaration struct {
+	Name token.Token
+	Kind DeclarationKind
+	// Node is the statement that declares the name: a VarStmt,
+	// FunctionStmt or ClassStmt, the FunctionStmt a parameter belongs to,
+	// or the TryStmt of a catch clause.
+	Node ast.Stmt
+	// Parent is the function, method or class the declaration is nested
+	// in, or nil at the top level.
+	Parent *Declaration
+	// Global is set for names declared outside every scope, which the
+	// interpreter looks up by name at runtime.
+	Global bool
+	// Shadows is the declaration of the same name in an enclosing scope,
+	// or an earlier global, that this one hides.
+	Shadows *Declaration
+}
+
+// Use is a name in a Variable or Assign expression and the declaration it
+// refers to.
+type Use struct {
+	Name        token.Token
+	Declaration *Declaration
+	// Assign is set when the name is assigned to rather than read.
+	Assign bool
+}
+
+// Declarations returns every declaration Resolve has seen, methods
+// included, in the order they were reached.
+func (r *Resolver) Declarations() []*Declaration {
+	return r.declarations
+}
+
+// Uses returns every use of a declared name Resolve has seen. Names that
+// are declared nowhere, such as natives, are left out.
+func (r *Resolver) Uses() []Use {
+	return r.uses
+}
+
+// record adds a declaration of name, nested in the current function or
+// class, and finds the one it shadows.
+func (r *Resolver) record(name token.Token, kind DeclarationKind, node ast.Stmt) *Declaration {
+	declaration := &Declaration{Name: name, Kind: kind, Node: node, Parent: r.container, Global: len(r.scopes) == 0}
+	r.declarations = append(r.declarations, declaration)
+	if kind == MethodDeclaration {
+		// Methods are reached through an object rather than by name.
+		return declaration
+	}
+	if !declaration.Global {
+		for idx := len(r.scopes) - 2; idx >= 0; idx-- {
+			if v, ok := r.scopes[idx][name.Lexeme]; ok && v.declaration != nil {
+				declaration.Shadows = v.declaration
+				return declaration
+			}
+		}
+	}
+	declaration.Shadows = r.globals[name.Lexeme]
+	return declaration
+}
+
+// use records a Variable or Assign expression's name. A name that isn't
+// declared yet may be a global declared further down, as when a function
+// calls one defined after it, so it is looked up again once the whole
+// program has been seen.
+func (r *Resolver) use(name token.Token, assign bool) {
+	for idx := len(r.scopes) - 1; idx >= 0; idx-- {
+		if v, ok := r.scopes[idx][name.Lexeme]; ok {
+			if v.declaration != nil {
+				r.uses = append(r.uses, Use{Name: name, Declaration: v.declaration, Assign: assign})
+			}
+			return
+		}
+	}
+	if declaration, ok := r.globals[name.Lexeme]; ok {
+		r.uses = append(r.uses, Use{Name: name, Declaration: declaration, Assign: assign})
+		return
+	}
+	r.unresolved = append(r.unresolved, Use{Name: name, Assign: assign})
+}
+
+// resolveGlobals looks up the uses that were unresolved when they were
+// reached against every global.
+func (r *Resolver) resolveGlobals() {
+	for _, use := range r.unresolved {
+		if declaration, ok := r.globals[use.Name.Lexeme]; ok {
+			use.Declaration = declaration
+			r.uses = append(r.uses, use)
+		}
+	}
+	r.unresolved = nil
+}
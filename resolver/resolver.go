@@ -0,0 +1,427 @@
+package resolver
+
+import (
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/ast"
+)
+
+type functionType int
+
+const (
+	functionTypeNone functionType = iota
+	functionTypeFunction
+	functionTypeInitializer
+	functionTypeMethod
+)
+
+type classType int
+
+const (
+	classTypeNone classType = iota
+	classTypeClass
+	classTypeSubclass
+)
+
+// variable is what a scope knows about one of its declarations: the slot it
+// will occupy in the runtime environment and whether its initializer has
+// finished. declaration is nil for the names the interpreter binds on its
+// own.
+type variable struct {
+	slot        int
+	defined     bool
+	declaration *Declaration
+}
+
+// Resolver walks the AST once before it runs and fills in the ast.Binding of
+// every Variable, Assign, This and Super node with the (depth, slot) of the
+// declaration it refers to. Names not found in any local scope are left
+// unresolved and treated as globals by the interpreter.
+//
+// It also repeats the parser's checks on where this, super, break and
+// continue may appear, for trees that didn't come from the parser, such as
+// those decoded by astjson.
+//
+// Along the way it records every declaration and every use of a name,
+// globals included, for Declarations and Uses.
+type Resolver struct {
+	scopes          []map[string]*variable
+	currentFunction functionType
+	currentClass    classType
+	inLoop          bool
+	reporter        *yaplErrors.Reporter
+
+	// container is the declaration of the function, method or class being
+	// walked, or nil at the top level.
+	container *Declaration
+	// globals holds the latest declaration of each global name reached.
+	globals      map[string]*Declaration
+	declarations []*Declaration
+	uses         []Use
+	unresolved   []Use
+}
+
+var _ ast.ExprVisitor = (*Resolver)(nil)
+var _ ast.StmtVisitor = (*Resolver)(nil)
+
+func NewResolver(reporter *yaplErrors.Reporter) *Resolver {
+	return &Resolver{
+		scopes:          []map[string]*variable{},
+		currentFunction: functionTypeNone,
+		currentClass:    classTypeNone,
+		reporter:        reporter,
+		globals:         map[string]*Declaration{},
+	}
+}
+
+func (r *Resolver) Resolve(statements []ast.Stmt) {
+	r.resolveStmts(statements)
+	r.resolveGlobals()
+}
+
+func (r *Resolver) resolveStmts(statements []ast.Stmt) {
+	for _, stmt := range statements {
+		r.resolveStmt(stmt)
+	}
+}
+
+func (r *Resolver) resolveStmt(stmt ast.Stmt) {
+	stmt.Accept(r)
+}
+
+func (r *Resolver) resolveExpr(expr ast.Expr) {
+	expr.Accept(r)
+}
+
+func (r *Resolver) beginScope() {
+	r.scopes = append(r.scopes, make(map[string]*variable))
+}
+
+func (r *Resolver) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+// declare reserves the next slot of the innermost scope for name. The order
+// of declarations here must match the order of Define calls at runtime.
+func (r *Resolver) declare(name token.Token, kind DeclarationKind, node ast.Stmt) *Declaration {
+	declaration := r.record(name, kind, node)
+	if len(r.scopes) == 0 {
+		return declaration
+	}
+	scope := r.scopes[len(r.scopes)-1]
+	if _, ok := scope[name.Lexeme]; ok {
+		r.reporter.Error(name, "Already a variable with this name in this scope.")
+		return declaration
+	}
+	scope[name.Lexeme] = &variable{slot: len(scope), defined: false, declaration: declaration}
+	return declaration
+}
+
+// define marks a declaration's initializer as finished. A global can only
+// be referred to from here on.
+func (r *Resolver) define(declaration *Declaration) {
+	if declaration.Global {
+		r.globals[declaration.Name.Lexeme] = declaration
+		return
+	}
+	if v, ok := r.scopes[len(r.scopes)-1][declaration.Name.Lexeme]; ok {
+		v.defined = true
+	}
+}
+
+// defineSynthetic declares and defines a name the interpreter binds on its
+// own, such as "this" and "super".
+func (r *Resolver) defineSynthetic(name string) {
+	scope := r.scopes[len(r.scopes)-1]
+	scope[name] = &variable{slot: len(scope), defined: true}
+}
+
+func (r *Resolver) resolveLocal(binding *ast.Binding, name token.Token) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if v, ok := r.scopes[i][name.Lexeme]; ok {
+			binding.Depth = len(r.scopes) - 1 - i
+			binding.Slot = v.slot
+			return
+		}
+	}
+	// Not found, so it's a global, whatever the binding said before.
+	*binding = *ast.NewBinding()
+}
+
+func (r *Resolver) resolveFunction(function ast.FunctionStmt, kind functionType, declaration *Declaration) {
+	enclosingFunction := r.currentFunction
+	r.currentFunction = kind
+	enclosingContainer := r.container
+	r.container = declaration
+	// break and continue must not escape a function body into an enclosing loop
+	enclosingLoop := r.inLoop
+	r.inLoop = false
+
+	r.beginScope()
+	for _, param := range function.Params {
+		r.define(r.declare(param, ParameterDeclaration, function))
+	}
+	r.resolveStmts(function.Body)
+	r.endScope()
+
+	r.currentFunction = enclosingFunction
+	r.inLoop = enclosingLoop
+	r.container = enclosingContainer
+}
+
+// Statement Visitors
+
+func (r *Resolver) VisitBlockStmtStmt(stmt ast.BlockStmt) interface{} {
+	r.beginScope()
+	r.resolveStmts(stmt.Statement)
+	r.endScope()
+	return nil
+}
+
+func (r *Resolver) VisitClassStmtStmt(stmt ast.ClassStmt) interface{} {
+	enclosingClass := r.currentClass
+	r.currentClass = classTypeClass
+
+	class := r.declare(stmt.Name, ClassDeclaration, stmt)
+	r.define(class)
+
+	if stmt.Superclass != nil {
+		r.currentClass = classTypeSubclass
+		r.resolveExpr(*stmt.Superclass)
+		r.beginScope()
+		r.defineSynthetic("super")
+	}
+
+	r.beginScope()
+	r.defineSynthetic("this")
+	enclosingContainer := r.container
+	r.container = class
+	for _, method := range stmt.Methods {
+		kind := functionTypeMethod
+		if method.Name.Lexeme == "init" {
+			kind = functionTypeInitializer
+		}
+		r.resolveFunction(method, kind, r.record(method.Name, MethodDeclaration, method))
+	}
+	r.container = enclosingContainer
+	r.endScope()
+
+	if stmt.Superclass != nil {
+		r.endScope()
+	}
+
+	r.currentClass = enclosingClass
+	return nil
+}
+
+func (r *Resolver) VisitExpressionStmtStmt(stmt ast.ExpressionStmt) interface{} {
+	r.resolveExpr(stmt.Expression)
+	return nil
+}
+
+func (r *Resolver) VisitFunctionStmtStmt(stmt ast.FunctionStmt) interface{} {
+	function := r.declare(stmt.Name, FunctionDeclaration, stmt)
+	r.define(function)
+	r.resolveFunction(stmt, functionTypeFunction, function)
+	return nil
+}
+
+func (r *Resolver) VisitIfStmtStmt(stmt ast.IfStmt) interface{} {
+	r.resolveExpr(stmt.Condition)
+	r.resolveStmt(stmt.ThenBranch)
+	if stmt.ElseBranch != nil {
+		r.resolveStmt(stmt.ElseBranch)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitPrintStmtStmt(stmt ast.PrintStmt) interface{} {
+	r.resolveExpr(stmt.Expression)
+	return nil
+}
+
+func (r *Resolver) VisitReturnStmtStmt(stmt ast.ReturnStmt) interface{} {
+	if r.currentFunction == functionTypeNone {
+		r.reporter.Error(stmt.Keyword, "Can't return from top-level code.")
+	}
+	if stmt.Value != nil {
+		if r.currentFunction == functionTypeInitializer {
+			r.reporter.Error(stmt.Keyword, "Can't return a value from an initializer.")
+		}
+		r.resolveExpr(stmt.Value)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitVarStmtStmt(stmt ast.VarStmt) interface{} {
+	declaration := r.declare(stmt.Name, VariableDeclaration, stmt)
+	if stmt.Initializer != nil {
+		r.resolveExpr(stmt.Initializer)
+	}
+	r.define(declaration)
+	return nil
+}
+
+func (r *Resolver) VisitWhileStmtStmt(stmt ast.WhileStmt) interface{} {
+	r.resolveExpr(stmt.Condition)
+	enclosingLoop := r.inLoop
+	r.inLoop = true
+	r.resolveStmt(stmt.Body)
+	r.inLoop = enclosingLoop
+	if stmt.Increment != nil {
+		r.resolveExpr(stmt.Increment)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitBreakStmtStmt(stmt ast.BreakStmt) interface{} {
+	if !r.inLoop {
+		r.reporter.Error(stmt.Keyword, "breakStatement can only exist inside valid iterator")
+	}
+	return nil
+}
+
+func (r *Resolver) VisitContinueStmtStmt(stmt ast.ContinueStmt) interface{} {
+	if !r.inLoop {
+		r.reporter.Error(stmt.Keyword, "continueStatemen can only exist inside valid iterator")
+	}
+	return nil
+}
+
+func (r *Resolver) VisitThrowStmtStmt(stmt ast.ThrowStmt) interface{} {
+	r.resolveExpr(stmt.Value)
+	return nil
+}
+
+func (r *Resolver) VisitTryStmtStmt(stmt ast.TryStmt) interface{} {
+	r.beginScope()
+	r.resolveStmts(stmt.Body)
+	r.endScope()
+
+	if stmt.CatchName != nil {
+		// The exception variable shares a scope with the catch body, the
+		// same way parameters share one with a function body.
+		r.beginScope()
+		r.define(r.declare(*stmt.CatchName, CatchDeclaration, stmt))
+		r.resolveStmts(stmt.CatchBody)
+		r.endScope()
+	}
+
+	if stmt.FinallyBody != nil {
+		r.beginScope()
+		r.resolveStmts(stmt.FinallyBody)
+		r.endScope()
+	}
+	return nil
+}
+
+// Expression Visitors
+
+func (r *Resolver) VisitAssignExpr(expr ast.Assign) interface{} {
+	r.resolveExpr(expr.Value)
+	r.use(expr.Name, true)
+	r.resolveLocal(expr.Binding, expr.Name)
+	return nil
+}
+
+func (r *Resolver) VisitBinaryExpr(expr ast.Binary) interface{} {
+	r.resolveExpr(expr.Left)
+	r.resolveExpr(expr.Right)
+	return nil
+}
+
+func (r *Resolver) VisitCallExpr(expr ast.Call) interface{} {
+	r.resolveExpr(expr.Callee)
+	for _, argument := range expr.Arguments {
+		r.resolveExpr(argument)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitGetExpr(expr ast.Get) interface{} {
+	r.resolveExpr(expr.Object)
+	return nil
+}
+
+func (r *Resolver) VisitGroupingExpr(expr ast.Grouping) interface{} {
+	r.resolveExpr(expr.Expression)
+	return nil
+}
+
+func (r *Resolver) VisitIndexExpr(expr ast.Index) interface{} {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return nil
+}
+
+func (r *Resolver) VisitIndexSetExpr(expr ast.IndexSet) interface{} {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	r.resolveExpr(expr.Value)
+	return nil
+}
+
+func (r *Resolver) VisitListExpr(expr ast.List) interface{} {
+	for _, element := range expr.Elements {
+		r.resolveExpr(element)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitMapExpr(expr ast.Map) interface{} {
+	for idx, key := range expr.Keys {
+		r.resolveExpr(key)
+		r.resolveExpr(expr.Values[idx])
+	}
+	return nil
+}
+
+func (r *Resolver) VisitLiteralExpr(expr ast.Literal) interface{} {
+	return nil
+}
+
+func (r *Resolver) VisitLogicalExpr(expr ast.Logical) interface{} {
+	r.resolveExpr(expr.Left)
+	r.resolveExpr(expr.Right)
+	return nil
+}
+
+func (r *Resolver) VisitSetExpr(expr ast.Set) interface{} {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Object)
+	return nil
+}
+
+func (r *Resolver) VisitSuperExpr(expr ast.Super) interface{} {
+	if r.currentClass == classTypeNone {
+		r.reporter.Error(expr.Keyword, "Can't use 'super' outside of a class.")
+	} else if r.currentClass != classTypeSubclass {
+		r.reporter.Error(expr.Keyword, "Can't use 'super' in a class with no superclass.")
+	}
+	r.resolveLocal(expr.Binding, expr.Keyword)
+	return nil
+}
+
+func (r *Resolver) VisitThisExpr(expr ast.This) interface{} {
+	if r.currentClass == classTypeNone {
+		r.reporter.Error(expr.Keyword, "Can't use 'this' outside of a class.")
+	}
+	r.resolveLocal(expr.Binding, expr.Keyword)
+	return nil
+}
+
+func (r *Resolver) VisitUnaryExpr(expr ast.Unary) interface{} {
+	r.resolveExpr(expr.Right)
+	return nil
+}
+
+func (r *Resolver) VisitVariableExpr(expr ast.Variable) interface{} {
+	if len(r.scopes) > 0 {
+		if v, ok := r.scopes[len(r.scopes)-1][expr.Name.Lexeme]; ok && !v.defined {
+			r.reporter.Error(expr.Name, "Can't read local variable in its own initializer.")
+		}
+	}
+	r.use(expr.Name, false)
+	r.resolveLocal(expr.Binding, expr.Name)
+	return nil
+}
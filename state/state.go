@@ -1,7 +0,0 @@
-package state
-
-var HadError bool = false
-var HadRuntimeError = false
-var CanInsertBreakOrContinueStatement = false
-var AbruptCompletion = false
-var ContinueException = false
@@ -0,0 +1,120 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/bytecode"
+)
+
+// thrown is returned by run when a throw statement executes. It is caught
+// by the innermost handler, or reported like the interpreter's Throw.
+type thrown struct {
+	value bytecode.Value
+	at    token.Token
+}
+
+func (t thrown) Error() string {
+	return t.runtimeError().Error()
+}
+
+func (t thrown) runtimeError() yaplErrors.RuntimeError {
+	if message, at, ok := errorFields(t.value); ok {
+		return yaplErrors.RuntimeError{
+			Token:   at,
+			Message: message,
+		}
+	}
+	return yaplErrors.RuntimeError{
+		Token:   t.at,
+		Message: fmt.Sprintf("Uncaught %s", t.value),
+	}
+}
+
+// uncaught turns an error that reached the top of the VM into the
+// RuntimeError Interpret returns, traced through the frames that were active
+// when it happened.
+func (vm *VM) uncaught(err error) error {
+	var runtimeError yaplErrors.RuntimeError
+	switch err := err.(type) {
+	case thrown:
+		runtimeError = err.runtimeError()
+	case yaplErrors.RuntimeError:
+		runtimeError = err
+	default:
+		return err
+	}
+	runtimeError.Trace = vm.trace()
+	return runtimeError
+}
+
+func (vm *VM) trace() []yaplErrors.Frame {
+	frames := make([]yaplErrors.Frame, 0, vm.frameCount)
+	for idx := 0; idx < vm.frameCount; idx++ {
+		frame := &vm.frames[idx]
+		function := frame.closure.Function.Name
+		if function == "" {
+			function = "<script>"
+		}
+		at := frame.position()
+		frames = append(frames, yaplErrors.Frame{
+			Function: function,
+			File:     vm.File,
+			Line:     at.Line,
+			Column:   at.Column,
+		})
+	}
+	return frames
+}
+
+// errorClass is the class of the values a catch clause receives for runtime
+// errors raised by the VM, mirroring the interpreter's Error instances.
+var errorClass = bytecode.NewClass("Error")
+
+func newErrorValue(err yaplErrors.RuntimeError) bytecode.Value {
+	instance := bytecode.NewInstance(errorClass)
+	instance.Fields["message"] = bytecode.ObjValue(err.Message)
+	instance.Fields["line"] = bytecode.NumberValue(float64(err.Token.Line))
+	instance.Fields["column"] = bytecode.NumberValue(float64(err.Token.Column))
+	instance.At = err.Token
+	return bytecode.ObjValue(instance)
+}
+
+func errorFields(value bytecode.Value) (string, token.Token, bool) {
+	instance, ok := value.Obj.(*bytecode.Instance)
+	if !ok || instance.Class != errorClass {
+		return "", token.Token{}, false
+	}
+	message := instance.Fields["message"]
+	if !message.IsString() {
+		return "", token.Token{}, false
+	}
+	return message.AsString(), instance.At, true
+}
+
+// catch hands err to the innermost installed handler, unwinding the frames
+// and stack above it. It reports false if there is no handler to take it.
+func (vm *VM) catch(err error) bool {
+	if len(vm.handlers) == 0 {
+		return false
+	}
+	var value bytecode.Value
+	switch err := err.(type) {
+	case thrown:
+		value = err.value
+	case yaplErrors.RuntimeError:
+		value = newErrorValue(err)
+	default:
+		return false
+	}
+
+	handler := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	vm.closeUpvalues(handler.stackTop)
+	vm.frameCount = handler.frameCount
+	vm.stackTop = handler.stackTop
+	vm.push(value)
+	vm.frames[vm.frameCount-1].ip = handler.ip
+	return true
+}
@@ -0,0 +1,79 @@
+package vm
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shubhdevelop/YAPL/bytecode"
+)
+
+// DefineNative registers fn as a global function called name that takes
+// exactly arity arguments.
+func (vm *VM) DefineNative(name string, arity int, fn bytecode.NativeFn) {
+	vm.globals[name] = bytecode.ObjValue(&bytecode.Native{
+		Name:     name,
+		Arity:    arity,
+		Function: fn,
+	})
+}
+
+// defineBuiltins mirrors the natives the Interpreter registers.
+func (vm *VM) defineBuiltins() {
+	vm.DefineNative("clock", 0, func(arguments []bytecode.Value) (bytecode.Value, error) {
+		return bytecode.NumberValue(float64(time.Now().UnixNano()) / float64(time.Second)), nil
+	})
+	vm.DefineNative("len", 1, func(arguments []bytecode.Value) (bytecode.Value, error) {
+		if arguments[0].IsString() {
+			return bytecode.NumberValue(float64(utf8.RuneCountInString(arguments[0].AsString()))), nil
+		}
+		if list, ok := arguments[0].Obj.(*bytecode.List); ok {
+			return bytecode.NumberValue(float64(len(list.Elements))), nil
+		}
+		if m, ok := arguments[0].Obj.(*bytecode.Map); ok {
+			return bytecode.NumberValue(float64(len(m.Keys))), nil
+		}
+		return bytecode.NilValue, fmt.Errorf("Can't take len() of %s.", arguments[0])
+	})
+	vm.DefineNative("keys", 1, func(arguments []bytecode.Value) (bytecode.Value, error) {
+		m, err := mapArgument("keys", arguments[0])
+		if err != nil {
+			return bytecode.NilValue, err
+		}
+		keys := append([]bytecode.Value{}, m.Keys...)
+		return bytecode.ObjValue(&bytecode.List{Elements: keys}), nil
+	})
+	vm.DefineNative("values", 1, func(arguments []bytecode.Value) (bytecode.Value, error) {
+		m, err := mapArgument("values", arguments[0])
+		if err != nil {
+			return bytecode.NilValue, err
+		}
+		values := make([]bytecode.Value, 0, len(m.Keys))
+		for _, key := range m.Keys {
+			values = append(values, m.Entries[key])
+		}
+		return bytecode.ObjValue(&bytecode.List{Elements: values}), nil
+	})
+	vm.DefineNative("has", 2, func(arguments []bytecode.Value) (bytecode.Value, error) {
+		m, err := mapArgument("has", arguments[0])
+		if err != nil {
+			return bytecode.NilValue, err
+		}
+		_, ok := m.Entries[arguments[1]]
+		return bytecode.BoolValue(ok), nil
+	})
+	vm.DefineNative("delete", 2, func(arguments []bytecode.Value) (bytecode.Value, error) {
+		m, err := mapArgument("delete", arguments[0])
+		if err != nil {
+			return bytecode.NilValue, err
+		}
+		return bytecode.BoolValue(m.Delete(arguments[1])), nil
+	})
+}
+
+func mapArgument(name string, argument bytecode.Value) (*bytecode.Map, error) {
+	if m, ok := argument.Obj.(*bytecode.Map); ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("%s() expects a map but got %s.", name, argument)
+}
@@ -0,0 +1,558 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/bytecode"
+)
+
+const (
+	FramesMax = 1024
+	StackMax  = FramesMax * 64
+)
+
+type callFrame struct {
+	closure *bytecode.Closure
+	ip      int
+	slots   int // index of the frame's slot zero on the value stack
+}
+
+// handler is an installed OP_TRY. When an error reaches it the VM drops
+// back to the recorded frame and stack height, pushes the thrown value and
+// resumes at ip.
+type handler struct {
+	frameCount int
+	stackTop   int
+	ip         int
+}
+
+// VM is a stack machine that runs functions produced by the compiler
+// package. It is an alternative backend to the tree-walking Interpreter and
+// prints the same output for the same program.
+type VM struct {
+	// Stdout receives the output of print statements and the runtime
+	// errors Interpret reports.
+	Stdout io.Writer
+	// File is the source name reported in runtime error traces.
+	File string
+	// Files, if set, holds the sources Interpret quotes runtime errors
+	// from.
+	Files *token.FileSet
+
+	frames       [FramesMax]callFrame
+	frameCount   int
+	stack        []bytecode.Value
+	stackTop     int
+	globals      map[string]bytecode.Value
+	openUpvalues *bytecode.Upvalue
+	handlers     []handler
+}
+
+func NewVM() *VM {
+	vm := &VM{
+		Stdout:  os.Stdout,
+		stack:   make([]bytecode.Value, StackMax),
+		globals: make(map[string]bytecode.Value),
+	}
+	vm.defineBuiltins()
+	return vm
+}
+
+// Globals returns the global variables defined so far, by name. The map is
+// the VM's own and must not be modified.
+func (vm *VM) Globals() map[string]bytecode.Value {
+	return vm.globals
+}
+
+// Interpret runs function, printing any runtime error to Stdout, and
+// returns that error.
+func (vm *VM) Interpret(function *bytecode.Function) error {
+	_, err := vm.Execute(function)
+	if err != nil {
+		// Same format as Interpreter.Interpret so both backends agree
+		fmt.Fprintln(vm.Stdout, "Runtime error:", yaplErrors.FormatError(err, vm.Files))
+	}
+	return err
+}
+
+// Execute runs function and returns the value the script returns, which is
+// nil unless it was compiled with CompileREPL. A runtime error or an
+// uncaught throw stops execution and is returned as a
+// yaplErrors.RuntimeError instead of printed. Globals survive from one call
+// to the next.
+func (vm *VM) Execute(function *bytecode.Function) (bytecode.Value, error) {
+	closure := bytecode.NewClosure(function)
+	vm.push(bytecode.ObjValue(closure))
+	err := vm.call(closure, 0)
+	for err == nil {
+		if err = vm.run(); err == nil {
+			break
+		}
+		if vm.catch(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		err = vm.uncaught(err)
+		vm.resetStack()
+		return bytecode.NilValue, err
+	}
+	return vm.pop(), nil
+}
+
+func (vm *VM) resetStack() {
+	vm.stackTop = 0
+	vm.frameCount = 0
+	vm.openUpvalues = nil
+	vm.handlers = nil
+}
+
+func (vm *VM) push(value bytecode.Value) {
+	vm.stack[vm.stackTop] = value
+	vm.stackTop++
+}
+
+func (vm *VM) pop() bytecode.Value {
+	vm.stackTop--
+	return vm.stack[vm.stackTop]
+}
+
+func (vm *VM) peek(distance int) bytecode.Value {
+	return vm.stack[vm.stackTop-1-distance]
+}
+
+// position is the source position of the instruction frame last read.
+func (frame *callFrame) position() token.Token {
+	return frame.closure.Function.Chunk.Positions[frame.ip-1].Token()
+}
+
+func (vm *VM) runtimeError(format string, args ...interface{}) error {
+	runtimeError := yaplErrors.RuntimeError{
+		Token:   vm.frames[vm.frameCount-1].position(),
+		Message: fmt.Sprintf(format, args...),
+	}
+	return runtimeError.ThrowRuntimeError()
+}
+
+func (vm *VM) call(closure *bytecode.Closure, argCount int) error {
+	if argCount != closure.Function.Arity {
+		return vm.runtimeError("Expected %d arguments but got %d.", closure.Function.Arity, argCount)
+	}
+	if vm.frameCount == FramesMax || vm.stackTop+256 > StackMax {
+		return vm.runtimeError("Stack overflow.")
+	}
+	frame := &vm.frames[vm.frameCount]
+	vm.frameCount++
+	frame.closure = closure
+	frame.ip = 0
+	frame.slots = vm.stackTop - argCount - 1
+	return nil
+}
+
+func (vm *VM) callValue(callee bytecode.Value, argCount int) error {
+	switch obj := callee.Obj.(type) {
+	case *bytecode.Closure:
+		return vm.call(obj, argCount)
+	case *bytecode.BoundMethod:
+		vm.stack[vm.stackTop-argCount-1] = obj.Receiver
+		return vm.call(obj.Method, argCount)
+	case *bytecode.Class:
+		vm.stack[vm.stackTop-argCount-1] = bytecode.ObjValue(bytecode.NewInstance(obj))
+		if initializer := findMethod(obj, "init"); initializer != nil {
+			return vm.call(initializer, argCount)
+		} else if argCount != 0 {
+			return vm.runtimeError("Expected 0 arguments but got %d.", argCount)
+		}
+		return nil
+	case *bytecode.Native:
+		if argCount != obj.Arity {
+			return vm.runtimeError("Expected %d arguments but got %d.", obj.Arity, argCount)
+		}
+		arguments := make([]bytecode.Value, argCount)
+		copy(arguments, vm.stack[vm.stackTop-argCount:vm.stackTop])
+		result, err := obj.Function(arguments)
+		if err != nil {
+			return vm.runtimeError("%s", err.Error())
+		}
+		vm.stackTop -= argCount + 1
+		vm.push(result)
+		return nil
+	}
+	return vm.runtimeError("Can only call functions and classes.")
+}
+
+func findMethod(class *bytecode.Class, name string) *bytecode.Closure {
+	if method, ok := class.Methods[name]; ok {
+		return method
+	}
+	return nil
+}
+
+func (vm *VM) bindMethod(class *bytecode.Class, name string) error {
+	method := findMethod(class, name)
+	if method == nil {
+		return vm.runtimeError("Undefined property '%s'.", name)
+	}
+	bound := &bytecode.BoundMethod{
+		Receiver: vm.peek(0),
+		Method:   method,
+	}
+	vm.pop()
+	vm.push(bytecode.ObjValue(bound))
+	return nil
+}
+
+// listPosition validates index against list with the same messages as the
+// interpreter's YaplList.
+func (vm *VM) listPosition(list *bytecode.List, index bytecode.Value) (int, error) {
+	if !index.IsNumber() || index.Number != math.Trunc(index.Number) {
+		return 0, vm.runtimeError("List index must be an integer.")
+	}
+	if index.Number < 0 {
+		return 0, vm.runtimeError("List index %s is negative.", index)
+	}
+	if index.Number >= float64(len(list.Elements)) {
+		return 0, vm.runtimeError("List index %s out of bounds for length %d.", index, len(list.Elements))
+	}
+	return int(index.Number), nil
+}
+
+// mapKeyError says what is wrong with key as a map key, or returns "" if
+// it is a valid one.
+func mapKeyError(key bytecode.Value) string {
+	switch {
+	case key.IsNumber() && math.IsNaN(key.Number):
+		return "Map keys can't be NaN."
+	case key.IsNumber(), key.IsString():
+		return ""
+	}
+	return "Map keys must be strings or numbers."
+}
+
+// captureUpvalue reuses an open upvalue for slot if one exists so that all
+// closures capturing the same variable share it. The open list is sorted
+// by slot, highest first.
+func (vm *VM) captureUpvalue(slot int) *bytecode.Upvalue {
+	var prev *bytecode.Upvalue
+	upvalue := vm.openUpvalues
+	for upvalue != nil && upvalue.Slot > slot {
+		prev = upvalue
+		upvalue = upvalue.Next
+	}
+	if upvalue != nil && upvalue.Slot == slot {
+		return upvalue
+	}
+
+	created := &bytecode.Upvalue{
+		Location: &vm.stack[slot],
+		Slot:     slot,
+		Next:     upvalue,
+	}
+	if prev == nil {
+		vm.openUpvalues = created
+	} else {
+		prev.Next = created
+	}
+	return created
+}
+
+func (vm *VM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.Slot >= last {
+		upvalue := vm.openUpvalues
+		upvalue.Closed = *upvalue.Location
+		upvalue.Location = &upvalue.Closed
+		vm.openUpvalues = upvalue.Next
+	}
+}
+
+func (vm *VM) run() error {
+	frame := &vm.frames[vm.frameCount-1]
+	code := frame.closure.Function.Chunk.Code
+	constants := frame.closure.Function.Chunk.Constants
+
+	readByte := func() byte {
+		b := code[frame.ip]
+		frame.ip++
+		return b
+	}
+	readShort := func() int {
+		frame.ip += 2
+		return int(code[frame.ip-2])<<8 | int(code[frame.ip-1])
+	}
+	readString := func() string {
+		return constants[readShort()].AsString()
+	}
+	// reload refreshes the cached frame after a call or return.
+	reload := func() {
+		frame = &vm.frames[vm.frameCount-1]
+		code = frame.closure.Function.Chunk.Code
+		constants = frame.closure.Function.Chunk.Constants
+	}
+
+	for {
+		switch bytecode.OpCode(readByte()) {
+		case bytecode.OP_CONSTANT:
+			vm.push(constants[readShort()])
+		case bytecode.OP_NIL:
+			vm.push(bytecode.NilValue)
+		case bytecode.OP_TRUE:
+			vm.push(bytecode.BoolValue(true))
+		case bytecode.OP_FALSE:
+			vm.push(bytecode.BoolValue(false))
+		case bytecode.OP_POP:
+			vm.pop()
+
+		case bytecode.OP_GET_LOCAL:
+			slot := int(readByte())
+			vm.push(vm.stack[frame.slots+slot])
+		case bytecode.OP_SET_LOCAL:
+			slot := int(readByte())
+			vm.stack[frame.slots+slot] = vm.peek(0)
+		case bytecode.OP_GET_GLOBAL:
+			name := readString()
+			value, ok := vm.globals[name]
+			if !ok {
+				return vm.runtimeError("Undefined variable '%s'.", name)
+			}
+			vm.push(value)
+		case bytecode.OP_DEFINE_GLOBAL:
+			vm.globals[readString()] = vm.pop()
+		case bytecode.OP_SET_GLOBAL:
+			name := readString()
+			if _, ok := vm.globals[name]; !ok {
+				return vm.runtimeError("Undefined variable '%s'.", name)
+			}
+			vm.globals[name] = vm.peek(0)
+		case bytecode.OP_GET_UPVALUE:
+			slot := readByte()
+			vm.push(*frame.closure.Upvalues[slot].Location)
+		case bytecode.OP_SET_UPVALUE:
+			slot := readByte()
+			*frame.closure.Upvalues[slot].Location = vm.peek(0)
+
+		case bytecode.OP_GET_PROPERTY:
+			instance, ok := vm.peek(0).Obj.(*bytecode.Instance)
+			if !ok {
+				return vm.runtimeError("Only instances have properties.")
+			}
+			name := readString()
+			if value, ok := instance.Fields[name]; ok {
+				vm.pop()
+				vm.push(value)
+				break
+			}
+			if err := vm.bindMethod(instance.Class, name); err != nil {
+				return err
+			}
+		case bytecode.OP_SET_PROPERTY:
+			instance, ok := vm.peek(1).Obj.(*bytecode.Instance)
+			if !ok {
+				return vm.runtimeError("Only instances have fields.")
+			}
+			instance.Fields[readString()] = vm.peek(0)
+			value := vm.pop()
+			vm.pop()
+			vm.push(value)
+		case bytecode.OP_GET_SUPER:
+			name := readString()
+			superclass := vm.pop().Obj.(*bytecode.Class)
+			if err := vm.bindMethod(superclass, name); err != nil {
+				return err
+			}
+
+		case bytecode.OP_EQUAL:
+			b := vm.pop()
+			a := vm.pop()
+			vm.push(bytecode.BoolValue(bytecode.ValuesEqual(a, b)))
+		case bytecode.OP_GREATER, bytecode.OP_LESS, bytecode.OP_SUBTRACT,
+			bytecode.OP_MULTIPLY, bytecode.OP_DIVIDE:
+			op := bytecode.OpCode(code[frame.ip-1])
+			if !vm.peek(0).IsNumber() || !vm.peek(1).IsNumber() {
+				return vm.runtimeError("Operands must be numbers.")
+			}
+			b := vm.pop().Number
+			a := vm.pop().Number
+			switch op {
+			case bytecode.OP_GREATER:
+				vm.push(bytecode.BoolValue(a > b))
+			case bytecode.OP_LESS:
+				vm.push(bytecode.BoolValue(a < b))
+			case bytecode.OP_SUBTRACT:
+				vm.push(bytecode.NumberValue(a - b))
+			case bytecode.OP_MULTIPLY:
+				vm.push(bytecode.NumberValue(a * b))
+			case bytecode.OP_DIVIDE:
+				vm.push(bytecode.NumberValue(a / b))
+			}
+		case bytecode.OP_ADD:
+			if vm.peek(0).IsNumber() && vm.peek(1).IsNumber() {
+				b := vm.pop().Number
+				a := vm.pop().Number
+				vm.push(bytecode.NumberValue(a + b))
+			} else if vm.peek(0).IsString() && vm.peek(1).IsString() {
+				b := vm.pop().AsString()
+				a := vm.pop().AsString()
+				vm.push(bytecode.ObjValue(a + b))
+			} else {
+				return vm.runtimeError("Operands must be two numbers or two strings.")
+			}
+		case bytecode.OP_NOT:
+			vm.push(bytecode.BoolValue(vm.pop().IsFalsey()))
+		case bytecode.OP_NEGATE:
+			if !vm.peek(0).IsNumber() {
+				return vm.runtimeError("Operand must be a number.")
+			}
+			vm.push(bytecode.NumberValue(-vm.pop().Number))
+
+		case bytecode.OP_PRINT:
+			fmt.Fprintln(vm.Stdout, vm.pop().String())
+
+		case bytecode.OP_JUMP:
+			offset := readShort()
+			frame.ip += offset
+		case bytecode.OP_JUMP_IF_FALSE:
+			offset := readShort()
+			if vm.peek(0).IsFalsey() {
+				frame.ip += offset
+			}
+		case bytecode.OP_LOOP:
+			offset := readShort()
+			frame.ip -= offset
+
+		case bytecode.OP_CALL:
+			argCount := int(readByte())
+			if err := vm.callValue(vm.peek(argCount), argCount); err != nil {
+				return err
+			}
+			reload()
+		case bytecode.OP_CLOSURE:
+			function := constants[readShort()].Obj.(*bytecode.Function)
+			closure := bytecode.NewClosure(function)
+			vm.push(bytecode.ObjValue(closure))
+			for idx := range closure.Upvalues {
+				isLocal := readByte()
+				index := int(readByte())
+				if isLocal == 1 {
+					closure.Upvalues[idx] = vm.captureUpvalue(frame.slots + index)
+				} else {
+					closure.Upvalues[idx] = frame.closure.Upvalues[index]
+				}
+			}
+		case bytecode.OP_CLOSE_UPVALUE:
+			vm.closeUpvalues(vm.stackTop - 1)
+			vm.pop()
+		case bytecode.OP_RETURN:
+			result := vm.pop()
+			vm.closeUpvalues(frame.slots)
+			vm.frameCount--
+			vm.stackTop = frame.slots
+			vm.push(result)
+			if vm.frameCount == 0 {
+				// The script's result is left for Execute.
+				return nil
+			}
+			reload()
+
+		case bytecode.OP_CLASS:
+			vm.push(bytecode.ObjValue(bytecode.NewClass(readString())))
+		case bytecode.OP_INHERIT:
+			superclass, ok := vm.peek(1).Obj.(*bytecode.Class)
+			if !ok {
+				return vm.runtimeError("Superclass must be a class.")
+			}
+			subclass := vm.peek(0).Obj.(*bytecode.Class)
+			// Copy-down inheritance: methods declared later override these.
+			for name, method := range superclass.Methods {
+				subclass.Methods[name] = method
+			}
+			vm.pop()
+		case bytecode.OP_BUILD_LIST:
+			count := readShort()
+			elements := make([]bytecode.Value, count)
+			copy(elements, vm.stack[vm.stackTop-count:vm.stackTop])
+			vm.stackTop -= count
+			vm.push(bytecode.ObjValue(&bytecode.List{Elements: elements}))
+		case bytecode.OP_BUILD_MAP:
+			count := readShort()
+			m := bytecode.NewMap()
+			base := vm.stackTop - count*2
+			for idx := 0; idx < count; idx++ {
+				key := vm.stack[base+idx*2]
+				if message := mapKeyError(key); message != "" {
+					return vm.runtimeError("%s", message)
+				}
+				m.Put(key, vm.stack[base+idx*2+1])
+			}
+			vm.stackTop = base
+			vm.push(bytecode.ObjValue(m))
+		case bytecode.OP_GET_INDEX:
+			index := vm.peek(0)
+			var value bytecode.Value
+			switch collection := vm.peek(1).Obj.(type) {
+			case *bytecode.List:
+				position, err := vm.listPosition(collection, index)
+				if err != nil {
+					return err
+				}
+				value = collection.Elements[position]
+			case *bytecode.Map:
+				if message := mapKeyError(index); message != "" {
+					return vm.runtimeError("%s", message)
+				}
+				entry, ok := collection.Entries[index]
+				if !ok {
+					return vm.runtimeError("Key '%s' not found in map.", index)
+				}
+				value = entry
+			default:
+				return vm.runtimeError("Only lists and maps can be indexed.")
+			}
+			vm.stackTop -= 2
+			vm.push(value)
+		case bytecode.OP_SET_INDEX:
+			index := vm.peek(1)
+			value := vm.peek(0)
+			switch collection := vm.peek(2).Obj.(type) {
+			case *bytecode.List:
+				position, err := vm.listPosition(collection, index)
+				if err != nil {
+					return err
+				}
+				collection.Elements[position] = value
+			case *bytecode.Map:
+				if message := mapKeyError(index); message != "" {
+					return vm.runtimeError("%s", message)
+				}
+				collection.Put(index, value)
+			default:
+				return vm.runtimeError("Only lists and maps can be indexed.")
+			}
+			vm.stackTop -= 3
+			vm.push(value)
+		case bytecode.OP_TRY:
+			offset := readShort()
+			vm.handlers = append(vm.handlers, handler{
+				frameCount: vm.frameCount,
+				stackTop:   vm.stackTop,
+				ip:         frame.ip + offset,
+			})
+		case bytecode.OP_END_TRY:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case bytecode.OP_THROW:
+			return thrown{
+				value: vm.pop(),
+				at:    frame.position(),
+			}
+		case bytecode.OP_METHOD:
+			method := vm.peek(0).Obj.(*bytecode.Closure)
+			class := vm.peek(1).Obj.(*bytecode.Class)
+			class.Methods[readString()] = method
+			vm.pop()
+		}
+	}
+}
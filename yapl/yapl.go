@@ -0,0 +1,101 @@
+// Package yapl embeds the YAPL interpreter in a Go program.
+//
+//	vm := yapl.New(yapl.Options{Stdout: &out})
+//	vm.Set("limit", 10.0)
+//	value, err := vm.Eval("limit * 2")
+//
+// Each VM owns its own globals, so any number of them can live in one
+// process. Errors are returned rather than printed: syntax errors as
+// yaplErrors.SyntaxError values joined with errors.Join, runtime errors and
+// uncaught throws as a yaplErrors.RuntimeError whose Trace lists the calls
+// that were active when it happened.
+package yapl
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	interpreter "github.com/shubhdevelop/YAPL/Interpreter"
+	"github.com/shubhdevelop/YAPL/Token"
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+	"github.com/shubhdevelop/YAPL/frontend"
+)
+
+// Value is a YAPL runtime value as seen from Go: nil, bool, float64,
+// string, or one of the interpreter's callable and instance types.
+type Value = interface{}
+
+type Options struct {
+	// Stdout receives the output of print statements. Defaults to
+	// io.Discard.
+	Stdout io.Writer
+	// Stderr, if set, receives a copy of every error as it is reported.
+	Stderr io.Writer
+	// File names the evaluated source in the Trace of runtime errors.
+	File string
+}
+
+type VM struct {
+	interpreter *interpreter.Interpreter
+	stderr      io.Writer
+}
+
+func New(opts Options) *VM {
+	stdout := opts.Stdout
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	interpreter := interpreter.NewInterpreter()
+	interpreter.Stdout = stdout
+	interpreter.File = opts.File
+	return &VM{
+		interpreter: interpreter,
+		stderr:      opts.Stderr,
+	}
+}
+
+// Eval runs src against the VM's globals. If the last statement is an
+// expression its value is returned; a trailing semicolon is optional.
+func (vm *VM) Eval(src string) (Value, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+	files := &token.FileSet{}
+	reporter := &yaplErrors.Reporter{Output: vm.stderr, Files: files}
+
+	result := frontend.Parse(src, frontend.Options{
+		File:                   files.Add(vm.interpreter.File, src),
+		Reporter:               reporter,
+		OptionalFinalSemicolon: true,
+	})
+	if len(result.Errors) > 0 {
+		return nil, errors.Join(result.Errors...)
+	}
+
+	value, err := vm.interpreter.Execute(result.Statements)
+	if err != nil && vm.stderr != nil {
+		io.WriteString(vm.stderr, err.Error()+"\n")
+	}
+	return value, err
+}
+
+// Set defines or overwrites a global variable.
+func (vm *VM) Set(name string, value Value) {
+	vm.interpreter.Globals.Define(name, value)
+}
+
+// Register exposes fn to scripts as a global function taking exactly arity
+// arguments. An error returned by fn becomes a YAPL runtime error at the
+// line of the call.
+func (vm *VM) Register(name string, arity int, fn func(arguments []Value) (Value, error)) {
+	vm.interpreter.DefineNative(name, arity, func(interpreter *interpreter.Interpreter, arguments []interface{}) (interface{}, error) {
+		return fn(arguments)
+	})
+}
+
+// Get reads a global variable.
+func (vm *VM) Get(name string) (Value, bool) {
+	value, ok := vm.interpreter.Globals.Values[name]
+	return value, ok
+}
@@ -0,0 +1,58 @@
+package yapl
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/shubhdevelop/YAPL/YaplErrors"
+)
+
+func TestAssignToUndefinedGlobal(t *testing.T) {
+	vm := New(Options{})
+	_, err := vm.Eval("x = 5;")
+	var runtimeError yaplErrors.RuntimeError
+	if !errors.As(err, &runtimeError) {
+		t.Fatalf("got %v, want a runtime error", err)
+	}
+	if runtimeError.Message != "Undefined variable 'x'." {
+		t.Fatalf("got %q", runtimeError.Message)
+	}
+	if _, ok := vm.Get("x"); ok {
+		t.Fatal("the assignment defined x")
+	}
+}
+
+// VMs share no state, so they can run side by side. Run with -race.
+func TestParallelVMs(t *testing.T) {
+	const program = `
+fun fib(n) { if (n < 2) return n; return fib(n - 2) + fib(n - 1); }
+var total = 0;
+for (var i = 0; i < 10; i = i + 1) total = total + fib(i);
+print total;
+twice(total) + limit`
+	for i := 0; i < 8; i++ {
+		limit := float64(i)
+		t.Run("", func(t *testing.T) {
+			t.Parallel()
+			var out bytes.Buffer
+			vm := New(Options{Stdout: &out})
+			vm.Set("limit", limit)
+			vm.Register("twice", 1, func(arguments []Value) (Value, error) {
+				return arguments[0].(float64) * 2, nil
+			})
+			for run := 0; run < 5; run++ {
+				value, err := vm.Eval(program)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if value != 176+limit {
+					t.Fatalf("got %v, want %v", value, 176+limit)
+				}
+			}
+			if want := "88\n88\n88\n88\n88\n"; out.String() != want {
+				t.Fatalf("printed %q, want %q", out.String(), want)
+			}
+		})
+	}
+}